@@ -0,0 +1,192 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 缓冲区满时阻塞写入方
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest 缓冲区满时丢弃当前记录
+	OverflowDropNewest
+	// OverflowDropOldest 缓冲区满时丢弃最早的记录
+	OverflowDropOldest
+)
+
+type asyncOption struct {
+	bufferSize    int
+	flushInterval time.Duration
+	policy        OverflowPolicy
+}
+
+var _ io.WriteCloser = (*asyncWriter)(nil)
+
+// asyncWriter 将日志记录放入有界环形缓冲区，由后台协程批量写入
+type asyncWriter struct {
+	writer io.Writer
+	opt    asyncOption
+
+	mu      sync.Mutex
+	notFull *sync.Cond
+	ring    [][]byte
+	head    int
+	size    int
+	closed  bool
+
+	dropped atomic.Uint64
+
+	wake    chan struct{}
+	flushCh chan chan struct{}
+	done    chan struct{}
+	exited  chan struct{}
+}
+
+func newAsyncWriter(w io.Writer, opt asyncOption) *asyncWriter {
+	if opt.bufferSize <= 0 {
+		opt.bufferSize = 1024
+	}
+
+	a := &asyncWriter{
+		writer:  w,
+		opt:     opt,
+		ring:    make([][]byte, opt.bufferSize),
+		wake:    make(chan struct{}, 1),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	a.notFull = sync.NewCond(&a.mu)
+
+	go a.run()
+	return a
+}
+
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		// 关闭后退化为同步写，避免退出阶段丢日志
+		return a.writer.Write(p)
+	}
+
+	if a.size == len(a.ring) {
+		switch a.opt.policy {
+		case OverflowDropNewest:
+			a.mu.Unlock()
+			a.dropped.Add(1)
+			return len(p), nil
+		case OverflowDropOldest:
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.size--
+			a.dropped.Add(1)
+		default:
+			for a.size == len(a.ring) && !a.closed {
+				a.notify()
+				a.notFull.Wait()
+			}
+			if a.closed {
+				a.mu.Unlock()
+				return a.writer.Write(p)
+			}
+		}
+	}
+
+	// slog的handler会复用p，必须拷贝
+	a.ring[(a.head+a.size)%len(a.ring)] = append([]byte(nil), p...)
+	a.size++
+	if a.opt.flushInterval <= 0 || a.size >= len(a.ring)/2 {
+		a.notify()
+	}
+	a.mu.Unlock()
+
+	return len(p), nil
+}
+
+func (a *asyncWriter) notify() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.exited)
+
+	var tick <-chan time.Time
+	if a.opt.flushInterval > 0 {
+		ticker := time.NewTicker(a.opt.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-a.wake:
+			a.drain()
+		case <-tick:
+			a.drain()
+		case ch := <-a.flushCh:
+			a.drain()
+			close(ch)
+		case <-a.done:
+			a.drain()
+			return
+		}
+	}
+}
+
+func (a *asyncWriter) drain() {
+	a.mu.Lock()
+	if a.size == 0 {
+		a.mu.Unlock()
+		return
+	}
+
+	var buf bytes.Buffer
+	for ; a.size > 0; a.size-- {
+		buf.Write(a.ring[a.head])
+		a.ring[a.head] = nil
+		a.head = (a.head + 1) % len(a.ring)
+	}
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	_, _ = a.writer.Write(buf.Bytes())
+}
+
+// Flush 阻塞直到已缓冲的记录全部写入
+func (a *asyncWriter) Flush() {
+	ch := make(chan struct{})
+	select {
+	case a.flushCh <- ch:
+		<-ch
+	case <-a.exited:
+	}
+}
+
+func (a *asyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close 写完缓冲区后停止后台协程，之后的写入直接同步写到底层writer
+func (a *asyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	close(a.done)
+	<-a.exited
+	return nil
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"io"
+	stdlog "log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BabySid/gobase"
+)
+
+// Handler 返回写入d的slog.Handler，用于slog.New，日志的调用位置由slog.Logger计算
+// 级别、采样、hook等设置与d相同，WithErr附加的属性不生效
+func (d *SLogger) Handler() slog.Handler {
+	h, ok := d.out.Handler().(*logHandler)
+	gobase.TrueF(ok, "unexpected handler %T", d.out.Handler())
+
+	n := *h
+	n.bridged = true
+	return &n
+}
+
+// SetDefault 将l设置为slog.Default()，同时标准库log的输出以LevelInfo写入l
+func SetDefault(l ExtLogger) {
+	slog.SetDefault(slog.New(l.Handler()))
+	RedirectStdLog(l, LevelInfo)
+}
+
+// RedirectStdLog 标准库log的输出以level写入l，每次调用为一条日志，返回的函数用于恢复原来的设置
+func RedirectStdLog(l ExtLogger, level slog.Level) (restore func()) {
+	flags, prefix, out := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdLogWriter{handler: l.Handler(), level: level})
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(out)
+	}
+}
+
+var _ io.Writer = (*stdLogWriter)(nil)
+
+type stdLogWriter struct {
+	handler slog.Handler
+	level   slog.Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, w.level) {
+		return len(p), nil
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, this function, log.(*Logger).output, log.Printf]
+	runtime.Callers(4, pcs[:])
+
+	r := slog.NewRecord(time.Now(), w.level, strings.TrimSuffix(string(p), "\n"), pcs[0])
+	return len(p), w.handler.Handle(ctx, r)
+}
+
+// callerFramesFrom 返回从pc所在的栈帧开始的调用栈
+func callerFramesFrom(pc uintptr) []gobase.CallFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	pcs = pcs[:n]
+	for i, p := range pcs {
+		if p == pc {
+			pcs = pcs[i:min(i+gobase.DefaultMaxCaller, n)]
+			break
+		}
+	}
+
+	var frames []gobase.CallFrame
+	fs := runtime.CallersFrames(pcs)
+	for {
+		f, more := fs.Next()
+		frames = append(frames, gobase.CallFrame{Line: f.Line, Function: f.Function, File: f.File})
+		if !more {
+			break
+		}
+	}
+	return frames
+}
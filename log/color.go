@@ -0,0 +1,144 @@
+package log
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+type ColorMode int
+
+const (
+	// ColorNever 不输出颜色，未调用WithColorful时的默认值
+	ColorNever ColorMode = iota
+	// ColorAuto 只对终端输出颜色
+	ColorAuto
+	// ColorAlways 总是输出颜色，e.g. docker logs、CI、less -R
+	ColorAlways
+)
+
+func isTerminal(w io.Writer) bool {
+	if f, ok := w.(*os.File); ok {
+		return term.IsTerminal(int(f.Fd()))
+	}
+	return false
+}
+
+// Palette 为nil的颜色不着色，Levels中没有的level使用低于它的最近level的颜色
+type Palette struct {
+	Time      termenv.Color
+	Levels    map[slog.Level]termenv.Color
+	Source    termenv.Color
+	Message   termenv.Color
+	AttrKey   termenv.Color
+	AttrValue termenv.Color
+}
+
+func DefaultPalette() Palette {
+	return Palette{
+		Time: termenv.ANSIBrightBlack,
+		Levels: map[slog.Level]termenv.Color{
+			LevelTrace: termenv.ANSIBrightBlack,
+			LevelDebug: termenv.ANSICyan,
+			LevelInfo:  termenv.ANSIGreen,
+			LevelWarn:  termenv.ANSIYellow,
+			LevelError: termenv.ANSIRed,
+			LevelPanic: termenv.ANSIMagenta,
+			LevelFatal: termenv.ANSIBrightRed,
+		},
+		Source:  termenv.RGBColor("#6C7B95"),
+		Message: termenv.ANSIBrightWhite,
+		AttrKey: termenv.RGBColor("#8A7CA8"),
+	}
+}
+
+// WithPalette 替换默认的配色，需配合WithColorful使用
+func WithPalette(p Palette) Option {
+	return func(opt *option) {
+		p.Levels = maps.Clone(p.Levels)
+		opt.palette = &p
+	}
+}
+
+func (p *Palette) level(level slog.Level) termenv.Color {
+	if c, ok := p.Levels[level]; ok {
+		return c
+	}
+
+	var c termenv.Color
+	found := false
+	var nearest slog.Level
+	for l, lc := range p.Levels {
+		if l <= level && (!found || l > nearest) {
+			c, nearest, found = lc, l, true
+		}
+	}
+	return c
+}
+
+// colorizer profile为termenv.Ascii时不输出颜色
+type colorizer struct {
+	profile termenv.Profile
+	palette *Palette
+}
+
+// newColorizer 环境变量优先于ColorAuto/ColorAlways:
+// FORCE_COLOR 非空且不为0/false时输出颜色，1/2/3分别对应16色、256色、真彩色
+// NO_COLOR 非空时不输出颜色
+func newColorizer(out io.Writer, mode ColorMode, palette *Palette) colorizer {
+	if palette == nil {
+		p := DefaultPalette()
+		palette = &p
+	}
+	c := colorizer{profile: termenv.Ascii, palette: palette}
+	if mode == ColorNever {
+		return c
+	}
+
+	force := strings.ToLower(os.Getenv("FORCE_COLOR"))
+	switch {
+	case force != "" && force != "0" && force != "false":
+		switch force {
+		case "1":
+			c.profile = termenv.ANSI
+		case "2":
+			c.profile = termenv.ANSI256
+		case "3":
+			c.profile = termenv.TrueColor
+		default:
+			c.profile = outputProfile(out)
+		}
+	case os.Getenv("NO_COLOR") != "":
+	case mode == ColorAlways:
+		c.profile = outputProfile(out)
+	case isTerminal(out):
+		c.profile = termenv.NewOutput(out).EnvColorProfile()
+	}
+	return c
+}
+
+// outputProfile 非终端时termenv检测结果为Ascii，此时使用256色
+func outputProfile(out io.Writer) termenv.Profile {
+	if isTerminal(out) {
+		if p := termenv.NewOutput(out).EnvColorProfile(); p != termenv.Ascii {
+			return p
+		}
+	}
+	return termenv.ANSI256
+}
+
+func (c colorizer) enabled() bool {
+	return c.profile != termenv.Ascii
+}
+
+func (c colorizer) paint(s string, color termenv.Color) string {
+	if !c.enabled() || s == "" || color == nil {
+		return s
+	}
+	return c.profile.String(s).Foreground(c.profile.Convert(color)).String()
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression int
+
+const (
+	CompressNone Compression = iota
+	CompressGzip
+	CompressZstd
+)
+
+// CompressExts 所有压缩格式的文件后缀
+var CompressExts = []string{CompressGzip.Ext(), CompressZstd.Ext()}
+
+func (c Compression) Ext() string {
+	switch c {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+func (c Compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("invalid compression:%d", c)
+	}
+}
+
+// NewDecompressReader 根据文件后缀返回解压后的reader，非压缩文件原样返回
+func NewDecompressReader(name string, r io.Reader) (io.ReadCloser, error) {
+	switch {
+	case hasExt(name, CompressGzip):
+		return gzip.NewReader(r)
+	case hasExt(name, CompressZstd):
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+func hasExt(name string, c Compression) bool {
+	ext := c.Ext()
+	return len(name) > len(ext) && name[len(name)-len(ext):] == ext
+}
+
+// compressFile 压缩src为src+ext，成功后删除src
+func compressFile(src string, c Compression) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst := src + c.Ext()
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+
+	cw, err := c.newWriter(out)
+	if err == nil {
+		if _, err = io.Copy(cw, in); err == nil {
+			err = cw.Close()
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// 保留原文件的修改时间，使maxAge依然按日志时间生效
+	_ = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
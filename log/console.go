@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var _ slog.Handler = (*consoleHandler)(nil)
+
+type consoleVerb int
+
+const (
+	verbLiteral consoleVerb = iota
+	verbTime
+	verbLevel
+	verbSource
+	verbMsg
+	verbAttrs
+)
+
+var consoleVerbs = map[string]consoleVerb{
+	"time":   verbTime,
+	"level":  verbLevel,
+	"source": verbSource,
+	"msg":    verbMsg,
+	"attrs":  verbAttrs,
+}
+
+type consoleToken struct {
+	verb    consoleVerb
+	literal string
+}
+
+// parseConsolePattern 解析形如 "%time %level %source %msg %attrs" 的格式，"%%" 表示字面量 "%"
+func parseConsolePattern(pattern string) ([]consoleToken, error) {
+	var tokens []consoleToken
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			tokens = append(tokens, consoleToken{verb: verbLiteral, literal: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			lit.WriteByte(pattern[i])
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '%' {
+			lit.WriteByte('%')
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(pattern) && pattern[j] >= 'a' && pattern[j] <= 'z' {
+			j++
+		}
+		verb, ok := consoleVerbs[pattern[i+1:j]]
+		if !ok {
+			return nil, fmt.Errorf("invalid console pattern verb %q in %q", pattern[i:j], pattern)
+		}
+		flush()
+		tokens = append(tokens, consoleToken{verb: verb})
+		i = j - 1
+	}
+	flush()
+	return tokens, nil
+}
+
+// consoleHandler 按pattern输出便于阅读的文本，颜色在编码时添加
+type consoleHandler struct {
+	core       handlerCore
+	tokens     []consoleToken
+	timeFormat string
+	colorizer  colorizer
+
+	mu  *sync.Mutex
+	out io.Writer
+}
+
+func newConsoleHandler(out io.Writer, pattern string, timeFormat string, cz colorizer, opts *slog.HandlerOptions) (*consoleHandler, error) {
+	tokens, err := parseConsolePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &consoleHandler{
+		core:       handlerCore{opts: opts},
+		tokens:     tokens,
+		timeFormat: timeFormat,
+		colorizer:  cz,
+		mu:         &sync.Mutex{},
+		out:        out,
+	}, nil
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.opts.Level == nil || level >= h.core.opts.Level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	skipSpace := false
+	for _, t := range h.tokens {
+		var s string
+		switch t.verb {
+		case verbLiteral:
+			s = t.literal
+			if skipSpace {
+				s = strings.TrimPrefix(s, " ")
+			}
+			buf.WriteString(s)
+			skipSpace = false
+			continue
+		case verbTime:
+			if !r.Time.IsZero() {
+				if a, ok := h.core.builtin(slog.Time(slog.TimeKey, r.Time)); ok {
+					s = h.colorizer.paint(formatValue(a.Value, h.timeFormat), h.colorizer.palette.Time)
+				}
+			}
+		case verbLevel:
+			if a, ok := h.core.builtin(slog.Any(slog.LevelKey, r.Level)); ok {
+				s = h.colorizer.paint(formatValue(a.Value, h.timeFormat), h.colorizer.palette.level(r.Level))
+			}
+		case verbSource:
+			if src := h.core.source(r); src != nil {
+				if a, ok := h.core.builtin(slog.String(slog.SourceKey, src.File+":"+strconv.Itoa(src.Line))); ok {
+					s = h.colorizer.paint(formatValue(a.Value, h.timeFormat), h.colorizer.palette.Source)
+				}
+			}
+		case verbMsg:
+			if a, ok := h.core.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+				s = h.colorizer.paint(a.Value.String(), h.colorizer.palette.Message)
+			}
+		case verbAttrs:
+			s = h.formatAttrs(r)
+		}
+		buf.WriteString(s)
+		skipSpace = s == ""
+	}
+
+	b := bytes.TrimRight(buf.Bytes(), " ")
+	b = append(b, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(b)
+	return err
+}
+
+func (h *consoleHandler) formatAttrs(r slog.Record) string {
+	var buf bytes.Buffer
+	flattenAttrs("", h.core.attrs(r), func(key string, v slog.Value) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		var kb bytes.Buffer
+		appendLogfmtKey(&kb, key)
+		kb.WriteByte('=')
+		buf.WriteString(h.colorizer.paint(kb.String(), h.colorizer.palette.AttrKey))
+		var vb bytes.Buffer
+		appendLogfmtValue(&vb, formatValue(v, h.timeFormat))
+		buf.WriteString(h.colorizer.paint(vb.String(), h.colorizer.palette.AttrValue))
+	})
+	return buf.String()
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.core = h.core.withAttrs(attrs)
+	return &n
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	n := *h
+	n.core = h.core.withGroup(name)
+	return &n
+}
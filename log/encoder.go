@@ -0,0 +1,239 @@
+package log
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type logFormat int
+
+const (
+	formatText logFormat = iota
+	formatJSON
+	formatLogfmt
+	formatConsole
+)
+
+const (
+	DefaultConsolePattern = "%time %level %source %msg %attrs"
+)
+
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// handlerCore 实现slog.Handler的WithAttrs/WithGroup语义，供自定义编码的handler复用
+type handlerCore struct {
+	opts *slog.HandlerOptions
+	goas []groupOrAttrs
+}
+
+func (c handlerCore) withAttrs(attrs []slog.Attr) handlerCore {
+	if len(attrs) == 0 {
+		return c
+	}
+	c.goas = append(c.goas[:len(c.goas):len(c.goas)], groupOrAttrs{attrs: attrs})
+	return c
+}
+
+func (c handlerCore) withGroup(name string) handlerCore {
+	if name == "" {
+		return c
+	}
+	c.goas = append(c.goas[:len(c.goas):len(c.goas)], groupOrAttrs{group: name})
+	return c
+}
+
+// attrs 返回该记录的全部属性，WithGroup产生的分组以slog.Group嵌套
+// 返回的属性已解析LogValuer、应用ReplaceAttr并去掉空属性和空分组
+func (c *handlerCore) attrs(r slog.Record) []slog.Attr {
+	groups := make([]string, 0, len(c.goas))
+	for _, goa := range c.goas {
+		if goa.group != "" {
+			groups = append(groups, goa.group)
+		}
+	}
+
+	cur := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		cur = c.appendAttr(cur, groups, a)
+		return true
+	})
+
+	for i := len(c.goas) - 1; i >= 0; i-- {
+		goa := c.goas[i]
+		if goa.group != "" {
+			groups = groups[:len(groups)-1]
+			if len(cur) > 0 {
+				cur = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(cur...)}}
+			}
+			continue
+		}
+
+		pre := make([]slog.Attr, 0, len(goa.attrs)+len(cur))
+		for _, a := range goa.attrs {
+			pre = c.appendAttr(pre, groups, a)
+		}
+		cur = append(pre, cur...)
+	}
+	return cur
+}
+
+func (c *handlerCore) appendAttr(dst []slog.Attr, groups []string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		children := a.Value.Group()
+		if len(children) == 0 {
+			return dst
+		}
+		sub := groups
+		if a.Key != "" {
+			sub = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		resolved := make([]slog.Attr, 0, len(children))
+		for _, child := range children {
+			resolved = c.appendAttr(resolved, sub, child)
+		}
+		if len(resolved) == 0 {
+			return dst
+		}
+		// 匿名分组的属性直接展开
+		if a.Key == "" {
+			return append(dst, resolved...)
+		}
+		return append(dst, slog.Attr{Key: a.Key, Value: slog.GroupValue(resolved...)})
+	}
+
+	if c.opts != nil && c.opts.ReplaceAttr != nil {
+		a = c.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+	return append(dst, a)
+}
+
+// builtin 对内置属性(time/level/source/msg)应用ReplaceAttr，返回false表示该属性被删除
+func (c *handlerCore) builtin(a slog.Attr) (slog.Attr, bool) {
+	if c.opts != nil && c.opts.ReplaceAttr != nil {
+		a = c.opts.ReplaceAttr(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+	return a, a.Key != ""
+}
+
+func (c *handlerCore) source(r slog.Record) *slog.Source {
+	if c.opts == nil || !c.opts.AddSource || r.PC == 0 {
+		return nil
+	}
+	fs := runtime.CallersFrames([]uintptr{r.PC})
+	f, _ := fs.Next()
+	return &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+}
+
+// flattenAttrs 将分组展开为以"."连接的key
+func flattenAttrs(prefix string, attrs []slog.Attr, fn func(key string, v slog.Value)) {
+	for _, a := range attrs {
+		key := a.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			flattenAttrs(key, a.Value.Group(), fn)
+			continue
+		}
+		fn(key, a.Value)
+	}
+}
+
+// formatValue 将属性值格式化为字符串，不做转义
+func formatValue(v slog.Value, timeFormat string) string {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindTime:
+		return v.Time().Format(timeFormat)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return x.Error()
+		case encoding.TextMarshaler:
+			b, err := x.MarshalText()
+			if err != nil {
+				return "!ERROR:" + err.Error()
+			}
+			return string(b)
+		case []byte:
+			return string(x)
+		case fmt.Stringer:
+			return x.String()
+		default:
+			return fmt.Sprintf("%+v", x)
+		}
+	default:
+		return v.String()
+	}
+}
+
+// appendLogfmtValue 按logfmt规则写入值，包含空格、引号、等号及控制字符时加引号
+func appendLogfmtValue(buf *bytes.Buffer, s string) {
+	if needsQuoting(s) {
+		buf.WriteString(strconv.Quote(s))
+		return
+	}
+	buf.WriteString(s)
+}
+
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// appendLogfmtKey logfmt的key中不允许出现空格、等号和引号
+func appendLogfmtKey(buf *bytes.Buffer, key string) {
+	if key == "" {
+		buf.WriteString("_")
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+		buf.WriteRune(r)
+	}
+}
+
+func defaultTimeFormat(format logFormat) string {
+	if format == formatConsole {
+		return "2006-01-02 15:04:05.000"
+	}
+	return time.RFC3339Nano
+}
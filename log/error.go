@@ -0,0 +1,61 @@
+package log
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+)
+
+const (
+	ErrorKey = "error"
+
+	maxErrorDepth = 8
+)
+
+// Err 返回key为"error"的属性，展开errors.Unwrap/errors.Join形成的错误链
+// e.g. fmt.Errorf("read config: %w", err) 输出为 error.msg="read config: open x: no such file" error.cause.msg=...
+func Err(err error) slog.Attr {
+	return slog.Any(ErrorKey, errorValue{err: err})
+}
+
+var _ slog.LogValuer = errorValue{}
+
+type errorValue struct {
+	err error
+}
+
+func (e errorValue) LogValue() slog.Value {
+	return errorChainValue(e.err, 0)
+}
+
+func errorChainValue(err error, depth int) slog.Value {
+	if err == nil {
+		return slog.StringValue("<nil>")
+	}
+
+	var causes []error
+	switch x := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes = x.Unwrap()
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			causes = []error{cause}
+		}
+	}
+
+	if len(causes) == 0 || depth >= maxErrorDepth {
+		return slog.StringValue(err.Error())
+	}
+
+	attrs := []slog.Attr{slog.String("msg", err.Error())}
+	if len(causes) == 1 {
+		attrs = append(attrs, slog.Attr{Key: "cause", Value: errorChainValue(causes[0], depth+1)})
+	} else {
+		joined := make([]slog.Attr, 0, len(causes))
+		for i, cause := range causes {
+			joined = append(joined, slog.Attr{Key: strconv.Itoa(i), Value: errorChainValue(cause, depth+1)})
+		}
+		attrs = append(attrs, slog.Attr{Key: "causes", Value: slog.GroupValue(joined...)})
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,135 @@
+// gormlog 为独立的module，只有使用gorm的项目才需要引入gorm依赖
+package gormlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/BabySid/gobase/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	DefaultSlowThreshold = 200 * time.Millisecond
+)
+
+type Config struct {
+	// SlowThreshold 超过该耗时的SQL以Warn输出，默认为DefaultSlowThreshold，小于0时不检查
+	SlowThreshold time.Duration
+	// LogLevel 默认为logger.Warn，logger.Info时所有SQL以Debug输出
+	LogLevel logger.LogLevel
+	// IgnoreRecordNotFoundError 不输出gorm.ErrRecordNotFound
+	IgnoreRecordNotFoundError bool
+	// ParameterizedQueries 输出的SQL不包含参数值
+	ParameterizedQueries bool
+}
+
+var _ logger.Interface = (*Logger)(nil)
+
+// Logger 实现gorm的logger.Interface，通过log.ExtLogger输出，日志的source为gorm外的调用位置
+// e.g. gorm.Open(dialector, &gorm.Config{Logger: gormlog.New(l.Named("gorm"), gormlog.Config{})})
+type Logger struct {
+	handler slog.Handler
+	cfg     Config
+}
+
+func New(l log.ExtLogger, cfg Config) *Logger {
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = DefaultSlowThreshold
+	}
+	if cfg.LogLevel == 0 {
+		cfg.LogLevel = logger.Warn
+	}
+	return &Logger{handler: l.Handler(), cfg: cfg}
+}
+
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	n := *l
+	n.cfg.LogLevel = level
+	return &n
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.cfg.LogLevel >= logger.Info {
+		l.output(ctx, log.LevelInfo, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.cfg.LogLevel >= logger.Warn {
+		l.output(ctx, log.LevelWarn, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.cfg.LogLevel >= logger.Error {
+		l.output(ctx, log.LevelError, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace 输出SQL错误及慢查询
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.cfg.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []slog.Attr {
+		sql, rows := fc()
+		return []slog.Attr{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		}
+	}
+
+	switch {
+	case err != nil && l.cfg.LogLevel >= logger.Error &&
+		!(l.cfg.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		l.output(ctx, log.LevelError, "sql error", append(attrs(), log.Err(err))...)
+	case l.cfg.SlowThreshold > 0 && elapsed > l.cfg.SlowThreshold && l.cfg.LogLevel >= logger.Warn:
+		l.output(ctx, log.LevelWarn, "slow sql", append(attrs(), slog.Duration("threshold", l.cfg.SlowThreshold))...)
+	case l.cfg.LogLevel >= logger.Info:
+		l.output(ctx, log.LevelDebug, "sql", attrs()...)
+	}
+}
+
+// ParamsFilter 实现gorm的logger.ParamsFilter
+func (l *Logger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.cfg.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (l *Logger) output(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, msg, callerPC())
+	r.AddAttrs(attrs...)
+	_ = l.handler.Handle(ctx, r)
+}
+
+// callerPC 跳过gorm及本包的调用，返回应用代码的调用位置
+func callerPC() uintptr {
+	var pcs [32]uintptr
+	// skip [runtime.Callers, this function, Logger.output]
+	n := runtime.Callers(3, pcs[:])
+	for _, pc := range pcs[:n] {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(f.Function, "gorm.io/") && !strings.HasPrefix(f.Function, selfPkg) {
+			return pc
+		}
+	}
+	return 0
+}
+
+var selfPkg = reflect.TypeOf(Logger{}).PkgPath() + "."
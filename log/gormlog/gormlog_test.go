@@ -0,0 +1,34 @@
+package gormlog_test
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/BabySid/gobase/log"
+	"github.com/BabySid/gobase/log/gormlog"
+	"gorm.io/gorm/logger"
+)
+
+func TestSource(t *testing.T) {
+	sink := log.NewMemorySink(10)
+	l := log.NewSLogger(log.WithSinkHandler(sink))
+	g := gormlog.New(l.Named("gorm"), gormlog.Config{LogLevel: logger.Info})
+
+	_, file, line, _ := runtime.Caller(0)
+	g.Warn(context.Background(), "slow %d", 1)
+
+	recs := sink.Records()
+	if len(recs) != 1 {
+		t.Fatalf("records=%d, want 1", len(recs))
+	}
+	src := recs[0].Source
+	if src == nil || filepath.Base(src.File) != filepath.Base(file) || src.Line != line+1 {
+		t.Errorf("source=%+v, want %s:%d", src, filepath.Base(file), line+1)
+	}
+	if !recs[0].Match(log.LevelWarn, "slow 1", slog.String(log.LoggerKey, "gorm")) {
+		t.Errorf("unexpected record %+v", recs[0])
+	}
+}
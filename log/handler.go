@@ -2,58 +2,158 @@ package log
 
 import (
 	"context"
-	"io"
 	"log/slog"
 	"runtime"
+
+	"github.com/BabySid/gobase"
 )
 
 var _ slog.Handler = (*logHandler)(nil)
 
+// logHandler 负责调用位置修正、采样及ctx属性提取，之后交给各sink处理
 type logHandler struct {
 	slog.Handler
-	out  io.Writer
-	opt  *slog.HandlerOptions
-	json bool
-	skip int
+	skip       int
+	ctxAttrs   []ContextAttrs
+	sampler    *sampler
+	stackLevel *slog.Level
+	redactor   *redactor
+	hooks      *hookRegistry
+	// core 记录With/WithGroup附加的属性，用于构造传给hook的记录
+	core handlerCore
+	// grouped 第一次WithGroup之后附加的分组和属性，在Handle中嵌套，
+	// 以便logger、ctx属性及stack等记录级的属性始终位于顶层
+	grouped handlerCore
+	// group WithGroup产生的分组路径，用于匹配脱敏规则
+	group string
+
+	// bridged 记录来自slog.Logger或标准库log，PC已由调用方计算
+	bridged bool
+
+	name    string
+	level   *slog.LevelVar
+	modules *moduleLevels
 }
 
-func newLogHandler(json bool, skip int, out io.Writer, opt *slog.HandlerOptions) *logHandler {
-	var handler slog.Handler
-	if json {
-		handler = slog.NewJSONHandler(out, opt)
-	} else {
-		handler = slog.NewTextHandler(out, opt)
+func newLogHandler(handler slog.Handler, opt *option) *logHandler {
+	return &logHandler{
+		Handler:    handler,
+		skip:       opt.skipCaller,
+		ctxAttrs:   opt.ctxAttrs,
+		sampler:    opt.sampler,
+		stackLevel: opt.stackLevel,
+		redactor:   opt.redactor,
+		hooks:      opt.hooks,
+		core:       handlerCore{opts: &slog.HandlerOptions{}},
+		level:      opt.level,
+		modules:    opt.modules,
 	}
+}
 
-	return &logHandler{
-		Handler: handler,
-		out:     out,
-		opt:     opt,
-		json:    json,
-		skip:    skip,
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	min, ok := h.modules.levelFor(h.name)
+	if !ok {
+		min = h.level.Level()
 	}
+	return level >= min && h.Handler.Enabled(ctx, level)
 }
 
 func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
-	if r.PC != 0 {
+	if h.sampler != nil && !h.sampler.sample(&r, h.emitSummary) {
+		return nil
+	}
+	return h.handle(ctx, r)
+}
+
+func (h *logHandler) handle(ctx context.Context, r slog.Record) error {
+	if r.PC != 0 && !h.bridged {
 		// fs := runtime.CallersFrames([]uintptr{r.PC})
 		// f, _ := fs.Next()
 
 		var pcs [1]uintptr
-		// skip [runtime.Callers, this function, this function's caller, slog.LogAttrs function, slog.LogAttrs's caller]
-		runtime.Callers(5+h.skip, pcs[:])
+		// skip [runtime.Callers, this function, Handle, Handle's caller, slog.LogAttrs function, slog.LogAttrs's caller]
+		runtime.Callers(6+h.skip, pcs[:])
 		r.PC = pcs[0]
 	}
 
-	return h.Handler.Handle(ctx, r)
+	var top []slog.Attr
+	if h.name != "" {
+		top = append(top, slog.String(LoggerKey, h.name))
+	}
+
+	if ctx != nil {
+		for _, fn := range h.ctxAttrs {
+			top = append(top, fn(ctx)...)
+		}
+	}
+
+	// 采样汇总日志没有调用位置，不附加调用栈
+	if h.stackLevel != nil && r.Level >= *h.stackLevel && r.PC != 0 {
+		// skip [runtime.Callers, gobase.GetCallerFrames, this function, Handle, slog.logAttrs, slog.LogAttrs, slog.LogAttrs's caller]
+		var frames []gobase.CallFrame
+		if h.bridged {
+			frames = callerFramesFrom(r.PC)
+		} else {
+			frames = gobase.GetCallerFrames(gobase.DefaultMaxCaller, 7+h.skip, true)
+		}
+		top = append(top, slog.Any(StackKey, stackTrace(frames)))
+	}
+
+	if h.redactor != nil {
+		r = h.redactor.record(h.group, r)
+		top = h.redactor.attrs("", top)
+	}
+
+	out := r
+	if len(h.grouped.goas) > 0 {
+		out = slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		out.AddAttrs(h.grouped.attrs(r)...)
+	}
+	out.AddAttrs(top...)
+
+	err := h.Handler.Handle(ctx, out)
+	if !h.hooks.empty() {
+		nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		nr.AddAttrs(h.core.attrs(r)...)
+		nr.AddAttrs(top...)
+		h.hooks.fire(ctx, nr)
+	}
+	return err
 }
 
 func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	handle := h.Handler.WithAttrs(attrs)
-	return &logHandler{
-		Handler: handle,
-		out:     h.out,
-		opt:     h.opt,
-		json:    h.json,
+	if len(attrs) == 0 {
+		return h
+	}
+	if h.redactor != nil {
+		attrs = h.redactor.attrs(h.group, attrs)
+	}
+	n := *h
+	if len(h.grouped.goas) > 0 {
+		n.grouped = h.grouped.withAttrs(attrs)
+	} else {
+		n.Handler = h.Handler.WithAttrs(attrs)
+	}
+	n.core = h.core.withAttrs(attrs)
+	return &n
+}
+
+func (h *logHandler) named(name string) *logHandler {
+	n := *h
+	if h.name != "" {
+		name = h.name + "." + name
+	}
+	n.name = name
+	return &n
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
 	}
+	n := *h
+	n.grouped = h.grouped.withGroup(name)
+	n.group = joinKey(h.group, name)
+	n.core = h.core.withGroup(name)
+	return &n
 }
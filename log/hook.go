@@ -0,0 +1,183 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// HookFunc 接收的记录包含With/WithGroup附加的属性，且已经过脱敏处理
+type HookFunc func(ctx context.Context, r slog.Record)
+
+// hookRegistry 由同一个SLogger派生的Logger共享
+type hookRegistry struct {
+	mu     sync.RWMutex
+	nextID int
+	hooks  []*hook
+
+	// 没有hook时跳过构造记录
+	count atomic.Int32
+}
+
+type hook struct {
+	id       int
+	minLevel slog.Level
+	fn       HookFunc
+	queue    *hookQueue
+}
+
+type hookEvent struct {
+	ctx context.Context
+	r   slog.Record
+	// flushed 不为nil时为flush标记，处理到该标记时关闭
+	flushed chan struct{}
+}
+
+// hookQueue 异步hook在独立的协程中按顺序执行，队列满时丢弃
+type hookQueue struct {
+	ch      chan hookEvent
+	dropped atomic.Uint64
+	done    chan struct{}
+	exited  chan struct{}
+}
+
+// AddHook 每条不低于minLevel的日志输出后同步调用fn，返回的函数用于移除该hook
+// e.g. 错误计数: logger.AddHook(LevelError, func(ctx context.Context, r slog.Record) { errCounter.Inc() })
+func (d *SLogger) AddHook(minLevel slog.Level, fn HookFunc) (remove func()) {
+	return d.opt.hooks.add(minLevel, fn, nil)
+}
+
+// AddAsyncHook 同AddHook，fn在后台协程中执行，不阻塞写日志，适用于发送webhook等耗时操作
+// 队列长度为queueSize，队列满时丢弃；Flush及Fatal会等待队列中的记录处理完
+func (d *SLogger) AddAsyncHook(minLevel slog.Level, fn HookFunc, queueSize int) (remove func()) {
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+	q := &hookQueue{
+		ch:     make(chan hookEvent, queueSize),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go q.run(fn)
+	return d.opt.hooks.add(minLevel, fn, q)
+}
+
+func (reg *hookRegistry) add(minLevel slog.Level, fn HookFunc, q *hookQueue) func() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.nextID++
+	h := &hook{id: reg.nextID, minLevel: minLevel, fn: fn, queue: q}
+	reg.hooks = append(reg.hooks, h)
+	reg.count.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			reg.remove(h.id)
+		})
+	}
+}
+
+func (reg *hookRegistry) remove(id int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for i, h := range reg.hooks {
+		if h.id != id {
+			continue
+		}
+		reg.hooks = append(reg.hooks[:i:i], reg.hooks[i+1:]...)
+		reg.count.Add(-1)
+		if h.queue != nil {
+			close(h.queue.done)
+		}
+		return
+	}
+}
+
+func (reg *hookRegistry) empty() bool {
+	return reg == nil || reg.count.Load() == 0
+}
+
+func (reg *hookRegistry) snapshot() []*hook {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.hooks
+}
+
+// fire 不持有锁执行hook，hook中可以再写日志，但不要写入会再次触发自身的日志
+func (reg *hookRegistry) fire(ctx context.Context, r slog.Record) {
+	for _, h := range reg.snapshot() {
+		if r.Level < h.minLevel {
+			continue
+		}
+		if h.queue == nil {
+			runHook(h.fn, ctx, r)
+			continue
+		}
+
+		select {
+		case <-h.queue.done:
+		case h.queue.ch <- hookEvent{ctx: context.WithoutCancel(ctx), r: r.Clone()}:
+		default:
+			h.queue.dropped.Add(1)
+		}
+	}
+}
+
+// flush 等待异步hook处理完已入队的记录
+func (reg *hookRegistry) flush() {
+	for _, h := range reg.snapshot() {
+		if h.queue == nil {
+			continue
+		}
+		flushed := make(chan struct{})
+		select {
+		case h.queue.ch <- hookEvent{flushed: flushed}:
+		case <-h.queue.exited:
+			continue
+		}
+		select {
+		case <-flushed:
+		case <-h.queue.exited:
+		}
+	}
+}
+
+func (q *hookQueue) run(fn HookFunc) {
+	defer close(q.exited)
+	for {
+		select {
+		case e := <-q.ch:
+			q.handle(fn, e)
+		case <-q.done:
+			// 移除后处理完剩余的记录再退出
+			for {
+				select {
+				case e := <-q.ch:
+					q.handle(fn, e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *hookQueue) handle(fn HookFunc, e hookEvent) {
+	if e.flushed != nil {
+		close(e.flushed)
+		return
+	}
+	runHook(fn, e.ctx, e.r)
+}
+
+// runHook hook的panic不影响写日志
+func runHook(fn HookFunc, ctx context.Context, r slog.Record) {
+	defer func() {
+		_ = recover()
+	}()
+	fn(ctx, r)
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultJournaldSocket = "/run/systemd/journal/socket"
+)
+
+var errJournaldUnsupported = errors.New("journald is not supported on this platform")
+
+type JournaldConfig struct {
+	Socket     string // 默认为 DefaultJournaldSocket
+	Identifier string // SYSLOG_IDENTIFIER，默认为进程名
+}
+
+var _ slog.Handler = (*JournaldHandler)(nil)
+
+// JournaldHandler 使用journald的native协议发送日志，属性转换为大写的journald字段，e.g. req.id -> REQ_ID
+// 通过 WithSinkHandler 接入SLogger
+type JournaldHandler struct {
+	core handlerCore
+	cfg  JournaldConfig
+
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// NewJournaldHandler 仅支持Linux，其他平台返回错误
+func NewJournaldHandler(cfg JournaldConfig) (*JournaldHandler, error) {
+	if !journaldSupported {
+		return nil, errJournaldUnsupported
+	}
+	if cfg.Socket == "" {
+		cfg.Socket = DefaultJournaldSocket
+	}
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+
+	addr := &net.UnixAddr{Name: cfg.Socket, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournaldHandler{
+		core: handlerCore{opts: &slog.HandlerOptions{AddSource: true}},
+		cfg:  cfg,
+		conn: conn,
+		addr: addr,
+	}, nil
+}
+
+func (h *JournaldHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *JournaldHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", r.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(SyslogSeverity(r.Level)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", h.cfg.Identifier)
+	if !r.Time.IsZero() {
+		// 与syslog协议中的时间格式一致
+		appendJournalField(&buf, "SYSLOG_TIMESTAMP", r.Time.Format(time.Stamp))
+	}
+	if src := h.core.source(r); src != nil {
+		appendJournalField(&buf, "CODE_FILE", src.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(src.Line))
+		appendJournalField(&buf, "CODE_FUNC", src.Function)
+	}
+	flattenAttrs("", h.core.attrs(r), func(key string, v slog.Value) {
+		if name := journalFieldName(key); name != "" {
+			appendJournalField(&buf, name, formatValue(v, time.RFC3339Nano))
+		}
+	})
+
+	_, _, err := h.conn.WriteMsgUnix(buf.Bytes(), nil, h.addr)
+	if err != nil && isMsgTooLarge(err) {
+		// 超过socket单个报文大小限制时，通过文件描述符传递
+		return sendJournalFd(h.conn, h.addr, buf.Bytes())
+	}
+	return err
+}
+
+func (h *JournaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.core = h.core.withAttrs(attrs)
+	return &n
+}
+
+func (h *JournaldHandler) WithGroup(name string) slog.Handler {
+	n := *h
+	n.core = h.core.withGroup(name)
+	return &n
+}
+
+func (h *JournaldHandler) Close() error {
+	return h.conn.Close()
+}
+
+// appendJournalField 值中包含换行时使用二进制格式: KEY\n<uint64 little-endian 长度><值>\n
+func appendJournalField(buf *bytes.Buffer, name string, value string) {
+	buf.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalReservedFields 由Handle填写的字段，同名的属性加上 ATTR_ 前缀，避免覆盖
+var journalReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"SYSLOG_TIMESTAMP":  true,
+	"SYSLOG_FACILITY":   true,
+	"SYSLOG_PID":        true,
+	"SYSLOG_RAW":        true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// journalFieldName 字段名只能包含大写字母、数字及下划线，不能以下划线或数字开头，最长64字符
+// 以下划线开头的为journald的受信任字段，去掉前导下划线；与保留字段同名时加上 ATTR_ 前缀
+func journalFieldName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), "_0123456789")
+	if journalReservedFields[name] {
+		name = "ATTR_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,36 @@
+//go:build linux
+
+package log
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+const journaldSupported = true
+
+func isMsgTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendJournalFd 写入/dev/shm下的临时文件后删除，再通过SCM_RIGHTS将文件描述符发送给journald
+func sendJournalFd(conn *net.UnixConn, addr *net.UnixAddr, data []byte) error {
+	f, err := os.CreateTemp("/dev/shm", "journal.")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+
+	rights := syscall.UnixRights(int(f.Fd()))
+	_, _, err = conn.WriteMsgUnix(nil, rights, addr)
+	return err
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournaldHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	h, err := NewJournaldHandler(JournaldConfig{Socket: path, Identifier: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r := slog.NewRecord(time.Now(), LevelError, "line1\nline2", 0)
+	r.AddAttrs(slog.String("message", "attr"), slog.Group("req", slog.Int("id", 7)))
+	if err = h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := buf[:n]
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len("line1\nline2")))
+	want := [][]byte{
+		append(append([]byte("MESSAGE\n"), size[:]...), "line1\nline2\n"...),
+		[]byte("PRIORITY=3\n"),
+		[]byte("SYSLOG_IDENTIFIER=app\n"),
+		[]byte("ATTR_MESSAGE=attr\n"),
+		[]byte("REQ_ID=7\n"),
+	}
+	for _, w := range want {
+		if !bytes.Contains(msg, w) {
+			t.Errorf("missing field %q in %q", w, msg)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package log
+
+import (
+	"net"
+)
+
+const journaldSupported = false
+
+func isMsgTooLarge(err error) bool {
+	return false
+}
+
+func sendJournalFd(conn *net.UnixConn, addr *net.UnixAddr, data []byte) error {
+	return errJournaldUnsupported
+}
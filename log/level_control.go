@@ -0,0 +1,94 @@
+package log
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/BabySid/gobase"
+)
+
+// LevelController 所有由同一个SLogger派生的Logger共享同一个level
+type LevelController interface {
+	Level() slog.Level
+	SetLevel(level slog.Level)
+}
+
+type levelPayload struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewLevelHandler 返回可挂载到管理端口的http.Handler
+// GET 返回当前level: {"level":"INFO"}
+// PUT 修改level, 请求体: {"level":"debug"}
+func NewLevelHandler(c LevelController) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+				return
+			}
+			level, err := ParseLevel(req.Level)
+			if err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+				return
+			}
+			c.SetLevel(level)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelPayload(w, http.StatusMethodNotAllowed, levelPayload{Error: "only GET and PUT are supported"})
+			return
+		}
+
+		writeLevelPayload(w, http.StatusOK, levelPayload{Level: LevelName(c.Level())})
+	})
+}
+
+func writeLevelPayload(w http.ResponseWriter, code int, payload levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+var levelSteps = []slog.Level{LevelTrace, LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+// RegisterLevelSignals 收到verbose信号时日志级别降低一档(输出更多日志)，收到quiet信号时升高一档
+// e.g. RegisterLevelSignals(gobase.NewSignalSet(), logger, syscall.SIGUSR1, syscall.SIGUSR2)
+func RegisterLevelSignals(set *gobase.SignalSet, c LevelController, verbose os.Signal, quiet os.Signal) {
+	set.Register(verbose, func(sig os.Signal) {
+		stepLevel(c, -1)
+	})
+	set.Register(quiet, func(sig os.Signal) {
+		stepLevel(c, 1)
+	})
+}
+
+func stepLevel(c LevelController, step int) {
+	cur := c.Level()
+	i := 0
+	for i < len(levelSteps)-1 && levelSteps[i] < cur {
+		i++
+	}
+
+	// 当前level不在预定义级别上时，先移动到相邻的预定义级别
+	if levelSteps[i] > cur && step > 0 {
+		step--
+	}
+	if levelSteps[i] < cur && step < 0 {
+		step++
+	}
+
+	i += step
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(levelSteps) {
+		i = len(levelSteps) - 1
+	}
+	c.SetLevel(levelSteps[i])
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+var _ slog.Handler = (*logfmtHandler)(nil)
+
+// logfmtHandler 输出logfmt格式: time=... level=INFO source=/path/to/file.go:12 msg="..." k=v group.k=v
+type logfmtHandler struct {
+	core       handlerCore
+	timeFormat string
+
+	mu  *sync.Mutex
+	out io.Writer
+}
+
+func newLogfmtHandler(out io.Writer, timeFormat string, opts *slog.HandlerOptions) *logfmtHandler {
+	return &logfmtHandler{
+		core:       handlerCore{opts: opts},
+		timeFormat: timeFormat,
+		mu:         &sync.Mutex{},
+		out:        out,
+	}
+}
+
+func (h *logfmtHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.opts.Level == nil || level >= h.core.opts.Level.Level()
+}
+
+func (h *logfmtHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+
+	field := func(key string, v slog.Value) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		appendLogfmtKey(&buf, key)
+		buf.WriteByte('=')
+		appendLogfmtValue(&buf, formatValue(v, h.timeFormat))
+	}
+
+	if !r.Time.IsZero() {
+		if a, ok := h.core.builtin(slog.Time(slog.TimeKey, r.Time)); ok {
+			field(a.Key, a.Value)
+		}
+	}
+	if a, ok := h.core.builtin(slog.Any(slog.LevelKey, r.Level)); ok {
+		field(a.Key, a.Value)
+	}
+	if src := h.core.source(r); src != nil {
+		if a, ok := h.core.builtin(slog.String(slog.SourceKey, src.File+":"+strconv.Itoa(src.Line))); ok {
+			field(a.Key, a.Value)
+		}
+	}
+	if a, ok := h.core.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		field(a.Key, a.Value)
+	}
+	flattenAttrs("", h.core.attrs(r), field)
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func (h *logfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.core = h.core.withAttrs(attrs)
+	return &n
+}
+
+func (h *logfmtHandler) WithGroup(name string) slog.Handler {
+	n := *h
+	n.core = h.core.withGroup(name)
+	return &n
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ import (
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 )
 
+// Logger 保持原有的方法集，新增的方法见 ExtLogger，已有的实现及mock不受影响
 type Logger interface {
 	Trace(msg string, attrs ...slog.Attr)
 	Debug(msg string, attrs ...slog.Attr)
@@ -25,7 +27,32 @@ type Logger interface {
 	WithErr(attrs ...slog.Attr) Logger
 }
 
-var _ Logger = (*SLogger)(nil)
+// ExtLogger SLogger及其派生的Logger实现的完整接口
+type ExtLogger interface {
+	Logger
+
+	Panic(msg string, attrs ...slog.Attr)
+	Fatal(msg string, attrs ...slog.Attr)
+
+	TraceContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	DebugContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	InfoContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	WarnContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	ErrorContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	PanicContext(ctx context.Context, msg string, attrs ...slog.Attr)
+	FatalContext(ctx context.Context, msg string, attrs ...slog.Attr)
+
+	Level() slog.Level
+	SetModuleLevels(spec string) error
+
+	Named(name string) ExtLogger
+	With(attrs ...slog.Attr) ExtLogger
+	WithGroup(name string) ExtLogger
+
+	Handler() slog.Handler
+}
+
+var _ ExtLogger = (*SLogger)(nil)
 
 const (
 	LevelTrace = slog.Level(-8)
@@ -33,30 +60,63 @@ const (
 	LevelInfo  = slog.LevelInfo
 	LevelWarn  = slog.LevelWarn
 	LevelError = slog.LevelError
+	// LevelPanic 输出后执行退出回调并panic
+	LevelPanic = slog.Level(12)
+	// LevelFatal 输出后执行退出回调并以状态码1退出
+	LevelFatal = slog.Level(16)
 )
 
 var levelNames = map[slog.Leveler]string{
 	LevelTrace: "TRACE",
+	LevelPanic: "PANIC",
+	LevelFatal: "FATAL",
 }
 
+// osExit 便于替换
+var osExit = os.Exit
+
 type rotateByTime struct {
 	pattern    string
 	maxAge     time.Duration
 	rotateTime time.Duration
 }
 
+type rotateBySize struct {
+	maxBytes   int64
+	maxBackups int
+}
+
 type option struct {
 	outFile string
 	errFile string
 
-	level *slog.LevelVar
+	level   *slog.LevelVar
+	modules *moduleLevels
 
 	skipCaller int
 
-	json         bool
-	rotateByTime *rotateByTime
+	format         logFormat
+	consolePattern string
+	timeFormat     string
+	rotateByTime   *rotateByTime
+	rotateBySize   *rotateBySize
+	compress       Compression
+	maxCount       int
 
-	colorful bool
+	async *asyncOption
+
+	ctxAttrs   []ContextAttrs
+	sampler    *sampler
+	stackLevel *slog.Level
+	redactor   *redactor
+	hooks      *hookRegistry
+
+	colorMode ColorMode
+	palette   *Palette
+
+	sinks    []sinkSpec
+	minLevel slog.Level
+	maxLevel slog.Level
 }
 
 func defaultRotateByTime() *rotateByTime {
@@ -69,6 +129,9 @@ func defaultRotateByTime() *rotateByTime {
 
 type Option func(*option)
 
+// ContextAttrs 从ctx中提取需要附加到每条日志的属性，如trace id、user id
+type ContextAttrs func(ctx context.Context) []slog.Attr
+
 const (
 	StdOut = "stdout"
 	StdErr = "stderr"
@@ -88,16 +151,45 @@ func WithErrFile(out string) Option {
 	}
 }
 
-// 主要对txt日志有效，建议dev环境使用
-func WithColorful() Option {
+// 建议dev环境使用，mode默认为ColorAuto，环境变量NO_COLOR/FORCE_COLOR的规则见newColorizer
+// 实际输出颜色时(e.g. ColorAuto且输出到终端)，文本格式使用 DefaultConsolePattern 输出，JSON格式按缩进格式输出
+func WithColorful(mode ...ColorMode) Option {
 	return func(opt *option) {
-		opt.colorful = true
+		opt.colorMode = ColorAuto
+		if len(mode) > 0 {
+			opt.colorMode = mode[0]
+		}
 	}
 }
 
 func WithJsonFormat() Option {
 	return func(opt *option) {
-		opt.json = true
+		opt.format = formatJSON
+	}
+}
+
+// WithLogfmtFormat 输出logfmt格式，分组属性的key以"."连接
+func WithLogfmtFormat() Option {
+	return func(opt *option) {
+		opt.format = formatLogfmt
+	}
+}
+
+// WithConsoleFormat 按pattern输出，支持 %time %level %source %msg %attrs，"%%"表示"%"
+// e.g. "%time [%level] %msg %attrs (%source)"，配合WithColorful在终端输出彩色日志
+func WithConsoleFormat(pattern string) Option {
+	return func(opt *option) {
+		_, err := parseConsolePattern(pattern)
+		gobase.TrueF(err == nil, "%v", err)
+		opt.format = formatConsole
+		opt.consolePattern = pattern
+	}
+}
+
+// WithTimeFormat 设置logfmt及console格式的时间格式，e.g. time.DateTime
+func WithTimeFormat(layout string) Option {
+	return func(opt *option) {
+		opt.timeFormat = layout
 	}
 }
 
@@ -116,31 +208,105 @@ func WithTimeRotate(pattern string, maxAge time.Duration, rotateTime time.Durati
 	}
 }
 
+// WithSizeRotate 文件超过maxBytes后切分为 file.1, file.2 ...，最多保留maxBackups个备份
+// 与WithTimeRotate同时使用时，在每个时间周期的文件内再按大小切分
+func WithSizeRotate(maxBytes int64, maxBackups int) Option {
+	return func(opt *option) {
+		gobase.TrueF(maxBytes > 0, "invalid maxBytes:%d", maxBytes)
+		opt.rotateBySize = &rotateBySize{
+			maxBytes:   maxBytes,
+			maxBackups: maxBackups,
+		}
+	}
+}
+
+// WithCompress 切分出的文件在后台压缩，log_sub.Consumer 可直接读取压缩后的文件
+func WithCompress(c Compression) Option {
+	return func(opt *option) {
+		opt.compress = c
+	}
+}
+
+// WithMaxCount 最多保留maxCount个切分出的文件(含压缩文件)，可与maxAge同时生效
+func WithMaxCount(maxCount int) Option {
+	return func(opt *option) {
+		opt.maxCount = maxCount
+	}
+}
+
+// WithAsync 日志先写入容量为bufferSize的环形缓冲区，由后台协程每flushInterval批量落盘
+// 缓冲区满时按policy处理，进程退出前通过 gobase.Exit 或 SLogger.Close 写完缓冲区
+func WithAsync(bufferSize int, flushInterval time.Duration, policy OverflowPolicy) Option {
+	return func(opt *option) {
+		opt.async = &asyncOption{
+			bufferSize:    bufferSize,
+			flushInterval: flushInterval,
+			policy:        policy,
+		}
+	}
+}
+
+// WithContextAttrs 注册ctx属性提取函数，可多次调用
+// 对每条日志生效，包括通过Handler()桥接的slog.Logger；非XxxContext方法的ctx为context.Background()
+func WithContextAttrs(fn ContextAttrs) Option {
+	return func(opt *option) {
+		opt.ctxAttrs = append(opt.ctxAttrs, fn)
+	}
+}
+
+// WithSampling 对相同(level, msg)的日志采样：每个tick内先输出first条，之后每thereafter条输出1条
+// thereafter<=0时丢弃其余日志，窗口结束时输出一条带被丢弃条数的汇总日志
+func WithSampling(tick time.Duration, first int, thereafter int) Option {
+	return func(opt *option) {
+		gobase.TrueF(tick > 0, "invalid tick:%v", tick)
+		opt.sampler = newSampler(tick, first, thereafter)
+	}
+}
+
 func WithLevel(lvl string) Option {
 	return func(opt *option) {
-		level := strings.ToLower(lvl)
-		switch level {
-		case "trace":
-			opt.level.Set(LevelTrace)
-		case "debug":
-			opt.level.Set(slog.LevelDebug)
-		case "info":
-			opt.level.Set(slog.LevelInfo)
-		case "warn":
-			opt.level.Set(slog.LevelWarn)
-		case "error":
-			opt.level.Set(slog.LevelError)
-		default:
-			panic(fmt.Errorf("invalid level:%s", lvl))
+		level, err := ParseLevel(lvl)
+		if err != nil {
+			panic(err)
 		}
+		opt.level.Set(level)
 	}
 }
 
+func ParseLevel(lvl string) (slog.Level, error) {
+	level := strings.ToLower(lvl)
+	switch level {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "panic":
+		return LevelPanic, nil
+	case "fatal":
+		return LevelFatal, nil
+	default:
+		return 0, fmt.Errorf("invalid level:%s", lvl)
+	}
+}
+
+func LevelName(level slog.Level) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return level.String()
+}
+
 type SLogger struct {
 	opt option
 
-	outWriter io.Writer
-	errWriter io.Writer
+	asyncWriters []*asyncWriter
+	closers      []io.Closer
 
 	slogOpt slog.HandlerOptions
 	out     *slog.Logger
@@ -150,8 +316,10 @@ type SLogger struct {
 func NewSLogger(opts ...Option) *SLogger {
 	log := SLogger{
 		opt: option{
-			level:    &slog.LevelVar{},
-			colorful: false,
+			level:     &slog.LevelVar{},
+			modules:   &moduleLevels{},
+			hooks:     &hookRegistry{},
+			colorMode: ColorNever,
 		},
 	}
 
@@ -159,54 +327,114 @@ func NewSLogger(opts ...Option) *SLogger {
 		opt(&log.opt)
 	}
 
-	log.outWriter = log.getWriter(log.opt.outFile)
-	log.errWriter = log.getWriter(log.opt.errFile)
-	gobase.TrueF(log.outWriter != nil || log.errWriter != nil, "outFile or errFile must be set at least one")
-
-	log.outWriter = gobase.GetNotNil(log.outWriter, log.errWriter)
-	log.errWriter = gobase.GetNotNil(log.errWriter, log.outWriter)
-	gobase.TrueF(log.outWriter != nil && log.errWriter != nil, "log.outWriter=%v log.errWriter=%v", log.outWriter, log.errWriter)
+	sinks := append(log.opt.legacySinks(), log.opt.sinks...)
+	gobase.TrueF(len(sinks) > 0, "outFile, errFile or sink must be set at least one")
 
 	log.slogOpt = slog.HandlerOptions{
 		AddSource: true,
-		Level:     log.opt.level,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.LevelKey {
-				level := a.Value.Any().(slog.Level)
-				levelLabel, exists := levelNames[level]
-				if !exists {
-					levelLabel = level.String()
-				}
-				a.Value = slog.StringValue(levelLabel)
-			}
-			return a
-		},
+		// 由logHandler按Logger名字判断level，sink不再过滤
+		Level:       slog.Level(math.MinInt),
+		ReplaceAttr: replaceLevelName,
+	}
+
+	handlers := make([]slog.Handler, 0, len(sinks))
+	for _, spec := range sinks {
+		handlers = append(handlers, log.getSinkHandler(spec))
 	}
 
-	log.out = log.getSlogger(log.outWriter)
-	log.err = log.getSlogger(log.errWriter)
+	if len(log.asyncWriters) > 0 {
+		// 退出时只停止异步写入，文件保持打开，保证其他退出回调的日志依然可以写入
+		gobase.RegisterAtExit(func() {
+			_ = log.closeAsync()
+		})
+	}
 
-	gobase.TrueF(log.out != nil && log.err != nil, "log.out=%v log.err=%v", log.out, log.err)
+	log.out = slog.New(newLogHandler(&multiHandler{handlers: handlers}, &log.opt))
+	log.err = log.out
 
 	return &log
 }
 
-func (d *SLogger) getSlogger(out io.Writer, attrs ...slog.Attr) *slog.Logger {
-	if out != nil {
-		if d.opt.colorful {
-			out = newColorWriter(out, d.opt.json)
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey {
+		a.Value = slog.StringValue(LevelName(a.Value.Any().(slog.Level)))
+	}
+	return a
+}
+
+func (d *SLogger) getSinkHandler(spec sinkSpec) slog.Handler {
+	opt := d.opt.sinkOption(spec)
+
+	if spec.handler != nil {
+		if c, ok := spec.handler.(io.Closer); ok {
+			d.closers = append(d.closers, c)
 		}
-		var handler slog.Handler = newLogHandler(d.opt.json, d.opt.skipCaller, out, &d.slogOpt)
-		if len(attrs) > 0 {
-			handler = handler.WithAttrs(attrs)
+		return &levelRangeHandler{
+			Handler: spec.handler,
+			min:     opt.minLevel,
+			max:     opt.maxLevel,
 		}
+	}
 
-		return slog.New(handler)
+	out := spec.writer
+	if out == nil {
+		out = d.getWriter(spec.file, &opt)
+	}
+	gobase.TrueF(out != nil, "invalid sink file:%s", spec.file)
+
+	// 需要在异步包装前判断是否为终端
+	cz := newColorizer(out, opt.colorMode, opt.palette)
+	if opt.async != nil {
+		out = d.getAsyncWriter(out, opt.async)
+	}
+
+	timeFormat := opt.timeFormat
+	if timeFormat == "" {
+		timeFormat = defaultTimeFormat(opt.format)
+	}
+
+	var handler slog.Handler
+	switch opt.format {
+	case formatJSON:
+		if cz.enabled() {
+			handler = newPrettyJSONHandler(out, cz, &d.slogOpt)
+		} else {
+			handler = slog.NewJSONHandler(out, &d.slogOpt)
+		}
+	case formatLogfmt:
+		handler = newLogfmtHandler(out, timeFormat, &d.slogOpt)
+	case formatConsole:
+		handler = d.getConsoleHandler(out, opt.consolePattern, timeFormat, cz)
+	default:
+		// 未实际输出颜色时(e.g. 文件、管道)保持slog文本格式
+		if cz.enabled() {
+			if opt.timeFormat == "" {
+				timeFormat = defaultTimeFormat(formatConsole)
+			}
+			handler = d.getConsoleHandler(out, DefaultConsolePattern, timeFormat, cz)
+		} else {
+			handler = slog.NewTextHandler(out, &d.slogOpt)
+		}
+	}
+
+	if opt.stackLevel != nil && opt.format != formatJSON {
+		handler = newStackTextHandler(handler, out)
+	}
+
+	return &levelRangeHandler{
+		Handler: handler,
+		min:     opt.minLevel,
+		max:     opt.maxLevel,
 	}
-	return nil
 }
 
-func (d *SLogger) getWriter(file string) io.Writer {
+func (d *SLogger) getConsoleHandler(out io.Writer, pattern string, timeFormat string, cz colorizer) slog.Handler {
+	handler, err := newConsoleHandler(out, pattern, timeFormat, cz, &d.slogOpt)
+	gobase.TrueF(err == nil, "init slog failed. err=%v", err)
+	return handler
+}
+
+func (d *SLogger) getWriter(file string, opt *option) io.Writer {
 	switch file {
 	case StdOut:
 		return os.Stdout
@@ -214,22 +442,76 @@ func (d *SLogger) getWriter(file string) io.Writer {
 		return os.Stderr
 	default:
 		if file != "" {
-			if d.opt.rotateByTime == nil {
-				d.opt.rotateByTime = defaultRotateByTime()
+			if opt.rotateBySize == nil && opt.rotateByTime == nil {
+				opt.rotateByTime = defaultRotateByTime()
 			}
+
+			if opt.rotateBySize != nil || opt.compress != CompressNone || opt.maxCount > 0 {
+				out, err := newRotateWriter(file, opt)
+				gobase.TrueF(err == nil, "init slog failed. err=%v", err)
+				d.closers = append(d.closers, out)
+				return out
+			}
+
 			out, err := rotatelogs.New(
-				file+d.opt.rotateByTime.pattern,
+				file+opt.rotateByTime.pattern,
 				rotatelogs.WithLinkName(file),
-				rotatelogs.WithMaxAge(d.opt.rotateByTime.maxAge),
-				rotatelogs.WithRotationTime(d.opt.rotateByTime.rotateTime),
+				rotatelogs.WithMaxAge(opt.rotateByTime.maxAge),
+				rotatelogs.WithRotationTime(opt.rotateByTime.rotateTime),
 			)
 			gobase.TrueF(err == nil, "init slog failed. err=%v", err)
+			d.closers = append(d.closers, out)
 			return out
 		}
 	}
 	return nil
 }
 
+func (d *SLogger) getAsyncWriter(w io.Writer, opt *asyncOption) io.Writer {
+	a := newAsyncWriter(w, *opt)
+	d.asyncWriters = append(d.asyncWriters, a)
+	return a
+}
+
+// Flush 将异步缓冲区中的日志写入底层writer
+func (d *SLogger) Flush() {
+	// hook中可能再写日志，先处理hook
+	d.opt.hooks.flush()
+	for _, a := range d.asyncWriters {
+		a.Flush()
+	}
+}
+
+// Dropped 返回异步模式下因缓冲区满而丢弃的日志条数
+func (d *SLogger) Dropped() uint64 {
+	var n uint64
+	for _, a := range d.asyncWriters {
+		n += a.Dropped()
+	}
+	return n
+}
+
+func (d *SLogger) closeAsync() error {
+	var err error
+	for _, a := range d.asyncWriters {
+		if e := a.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close 写完缓冲区并关闭日志文件
+func (d *SLogger) Close() error {
+	err := d.closeAsync()
+	for _, c := range d.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
 // Trace implements Logger.
 func (d *SLogger) Trace(msg string, attrs ...slog.Attr) {
 	d.out.LogAttrs(context.Background(), LevelTrace, msg, attrs...)
@@ -255,19 +537,111 @@ func (d *SLogger) Error(msg string, attrs ...slog.Attr) {
 	d.err.LogAttrs(context.Background(), slog.LevelError, msg, attrs...)
 }
 
+// Panic implements ExtLogger.
+func (d *SLogger) Panic(msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(context.Background(), LevelPanic, msg, attrs...)
+	d.panic(msg)
+}
+
+// Fatal implements ExtLogger.
+func (d *SLogger) Fatal(msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(context.Background(), LevelFatal, msg, attrs...)
+	d.fatal()
+}
+
+// TraceContext implements ExtLogger.
+func (d *SLogger) TraceContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.out.LogAttrs(ctx, LevelTrace, msg, attrs...)
+}
+
+// DebugContext implements ExtLogger.
+func (d *SLogger) DebugContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.out.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// InfoContext implements ExtLogger.
+func (d *SLogger) InfoContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.out.LogAttrs(ctx, slog.LevelInfo, msg, attrs...)
+}
+
+// WarnContext implements ExtLogger.
+func (d *SLogger) WarnContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(ctx, slog.LevelWarn, msg, attrs...)
+}
+
+// ErrorContext implements ExtLogger.
+func (d *SLogger) ErrorContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(ctx, slog.LevelError, msg, attrs...)
+}
+
+// PanicContext implements ExtLogger.
+func (d *SLogger) PanicContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(ctx, LevelPanic, msg, attrs...)
+	d.panic(msg)
+}
+
+// FatalContext implements ExtLogger.
+func (d *SLogger) FatalContext(ctx context.Context, msg string, attrs ...slog.Attr) {
+	d.err.LogAttrs(ctx, LevelFatal, msg, attrs...)
+	d.fatal()
+}
+
+// panic 退出回调只会执行一次，recover后不会再次执行
+func (d *SLogger) panic(msg string) {
+	d.Flush()
+	gobase.Exit()
+	panic(msg)
+}
+
+// fatal 退出回调中依然可以写日志，之后再关闭日志
+func (d *SLogger) fatal() {
+	d.Flush()
+	gobase.Exit()
+	_ = d.Close()
+	osExit(1)
+}
+
 // SetLevel implements Logger.
 func (d *SLogger) SetLevel(level slog.Level) {
 	d.opt.level.Set(level)
 }
 
+// Level implements ExtLogger.
+func (d *SLogger) Level() slog.Level {
+	return d.opt.level.Level()
+}
+
+// With 同时为out和err附加属性
+func (d *SLogger) With(attrs ...slog.Attr) ExtLogger {
+	if len(attrs) == 0 {
+		return d
+	}
+
+	n := d.clone()
+	n.out = slog.New(n.out.Handler().WithAttrs(attrs))
+	n.err = slog.New(n.err.Handler().WithAttrs(attrs))
+	return n
+}
+
+// WithGroup 之后附加的属性(包括日志本身的attrs)都归入name分组
+func (d *SLogger) WithGroup(name string) ExtLogger {
+	if name == "" {
+		return d
+	}
+
+	n := d.clone()
+	n.out = n.out.WithGroup(name)
+	n.err = n.err.WithGroup(name)
+	return n
+}
+
 func (d *SLogger) WithOut(attrs ...slog.Attr) Logger {
 	if len(attrs) == 0 {
 		return d
 	}
 
 	n := d.clone()
-	n.out = n.getSlogger(n.outWriter, attrs...)
-	gobase.TrueF(n.out != nil && n.err != nil, "log.out=%v log.err=%v", n.out, n.err)
+	n.out = slog.New(n.out.Handler().WithAttrs(attrs))
 	return n
 }
 
@@ -277,8 +651,7 @@ func (d *SLogger) WithErr(attrs ...slog.Attr) Logger {
 	}
 
 	n := d.clone()
-	n.err = n.getSlogger(n.errWriter, attrs...)
-	gobase.TrueF(n.out != nil && n.err != nil, "log.out=%v log.err=%v", n.out, n.err)
+	n.err = slog.New(n.err.Handler().WithAttrs(attrs))
 	return n
 }
 
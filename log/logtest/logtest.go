@@ -0,0 +1,128 @@
+package logtest
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/BabySid/gobase/log"
+)
+
+const (
+	// DefaultCapacity 测试日志最多保存的条数
+	DefaultCapacity = 10000
+
+	tbPattern = "%source: %level %msg %attrs"
+)
+
+// TestLogger 记录写入的日志用于断言，同时输出到t.Log，只在测试失败或-v时显示
+// t.Log显示的位置在logtest内部，因此每行以写日志处的 file:line 开头
+// e.g.
+//
+//	logger := logtest.NewTestLogger(t)
+//	svc := NewService(logger)
+//	svc.Do()
+//	logger.AssertRecord(log.LevelWarn, "retry", slog.Int("attempt", 1))
+type TestLogger struct {
+	*log.SLogger
+	Sink *log.MemorySink
+
+	t testing.TB
+}
+
+// NewTestLogger 默认level为trace，opts可以覆盖默认设置
+func NewTestLogger(t testing.TB, opts ...log.Option) *TestLogger {
+	sink := log.NewMemorySink(DefaultCapacity)
+	tw := &tbWriter{t: t}
+	t.Cleanup(tw.stop)
+
+	opts = append([]log.Option{
+		log.WithLevel("trace"),
+		log.WithSinkHandler(sink),
+		log.WithSinkWriter(tw, log.WithConsoleFormat(tbPattern)),
+	}, opts...)
+
+	return &TestLogger{
+		SLogger: log.NewSLogger(opts...),
+		Sink:    sink,
+		t:       t,
+	}
+}
+
+func (l *TestLogger) Records() []log.MemoryRecord {
+	return l.Sink.Records()
+}
+
+// HasRecord 存在level和msg相同，且包含attrs中全部属性的日志，分组属性可以用slog.Group或以"."连接的key匹配
+func (l *TestLogger) HasRecord(level slog.Level, msg string, attrs ...slog.Attr) bool {
+	return l.Sink.HasRecord(level, msg, attrs...)
+}
+
+// AssertRecord 不存在匹配的日志时标记测试失败，并输出已记录的日志
+func (l *TestLogger) AssertRecord(level slog.Level, msg string, attrs ...slog.Attr) {
+	l.t.Helper()
+	if !l.HasRecord(level, msg, attrs...) {
+		l.t.Errorf("no record matches level=%s msg=%q attrs=%v\nrecords:\n%s",
+			log.LevelName(level), msg, attrs, l.dump())
+	}
+}
+
+// AssertNoRecord 存在匹配的日志时标记测试失败
+func (l *TestLogger) AssertNoRecord(level slog.Level, msg string, attrs ...slog.Attr) {
+	l.t.Helper()
+	if l.HasRecord(level, msg, attrs...) {
+		l.t.Errorf("unexpected record level=%s msg=%q attrs=%v", log.LevelName(level), msg, attrs)
+	}
+}
+
+func (l *TestLogger) Reset() {
+	l.Sink.Reset()
+}
+
+func (l *TestLogger) dump() string {
+	var b strings.Builder
+	for _, r := range l.Records() {
+		fmt.Fprintf(&b, "\t%s %s", log.LevelName(r.Level), r.Message)
+		for _, a := range r.Attrs {
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// tbWriter 测试结束后调用t.Log会panic，因此在Cleanup后丢弃日志
+type tbWriter struct {
+	mu      sync.Mutex
+	t       testing.TB
+	stopped bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.stopped {
+		w.t.Log(shortSource(string(bytes.TrimRight(p, "\n"))))
+	}
+	return len(p), nil
+}
+
+// shortSource 与testing的输出一致，行首的source只保留文件名
+func shortSource(line string) string {
+	src, rest, ok := strings.Cut(line, ": ")
+	if !ok {
+		return line
+	}
+	return filepath.Base(src) + ": " + rest
+}
+
+func (w *tbWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+}
@@ -0,0 +1,82 @@
+package logtest
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/BabySid/gobase/log"
+)
+
+// fakeTB 记录Errorf及Log，用于验证断言失败的情况及输出内容
+type fakeTB struct {
+	testing.TB
+	errs []string
+	logs []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Log(args ...any) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func TestCapture(t *testing.T) {
+	logger := NewTestLogger(t)
+	logger.Info("hello", slog.String("user", "bob"), slog.Group("req", slog.Int("id", 7)))
+	logger.Warn("retry", slog.Int("attempt", 1))
+
+	if n := len(logger.Records()); n != 2 {
+		t.Fatalf("records=%d, want 2", n)
+	}
+	if !logger.HasRecord(log.LevelInfo, "hello", slog.String("user", "bob"), slog.Int("req.id", 7)) {
+		t.Error("hello record not found")
+	}
+	if logger.HasRecord(log.LevelInfo, "hello", slog.String("user", "alice")) {
+		t.Error("unexpected match with different attr")
+	}
+	if logger.HasRecord(log.LevelError, "retry") {
+		t.Error("unexpected match with different level")
+	}
+
+	logger.AssertRecord(log.LevelWarn, "retry", slog.Int("attempt", 1))
+	logger.AssertNoRecord(log.LevelWarn, "retry", slog.Int("attempt", 2))
+
+	logger.Reset()
+	if n := len(logger.Records()); n != 0 {
+		t.Fatalf("records=%d after reset, want 0", n)
+	}
+}
+
+func TestAssertFailure(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	logger := NewTestLogger(ft)
+	logger.Error("boom", slog.String("code", "E1"))
+
+	logger.AssertRecord(log.LevelError, "boom", slog.String("code", "E2"))
+	logger.AssertNoRecord(log.LevelError, "boom")
+	logger.AssertRecord(log.LevelError, "boom", slog.String("code", "E1"))
+	logger.AssertNoRecord(log.LevelInfo, "boom")
+
+	if len(ft.errs) != 2 {
+		t.Fatalf("errors=%d, want 2: %q", len(ft.errs), ft.errs)
+	}
+}
+
+func TestReportedLocation(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	logger := NewTestLogger(ft)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello", slog.Int("n", 1))
+
+	want := fmt.Sprintf("logtest_test.go:%d: INFO hello n=1", line+1)
+	if len(ft.logs) != 1 || ft.logs[0] != want {
+		t.Errorf("logs=%q, want %q", ft.logs, want)
+	}
+}
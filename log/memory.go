@@ -0,0 +1,251 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryRecord 保存在内存中的日志，分组属性的key以"."连接，e.g. req.id
+type MemoryRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Source  *slog.Source
+	Attrs   []slog.Attr
+}
+
+// Attr 返回key对应的属性值
+func (r MemoryRecord) Attr(key string) (slog.Value, bool) {
+	for _, a := range r.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// Match level和msg相同，且包含attrs中的全部属性
+func (r MemoryRecord) Match(level slog.Level, msg string, attrs ...slog.Attr) bool {
+	if r.Level != level || r.Message != msg {
+		return false
+	}
+
+	matched := true
+	flattenAttrs("", attrs, func(key string, want slog.Value) {
+		if !matched {
+			return
+		}
+		got, ok := r.Attr(key)
+		matched = ok && valueEqual(got, want.Resolve())
+	})
+	return matched
+}
+
+func valueEqual(a slog.Value, b slog.Value) bool {
+	if a.Kind() == slog.KindAny || b.Kind() == slog.KindAny {
+		return reflect.DeepEqual(a.Any(), b.Any())
+	}
+	return a.Equal(b)
+}
+
+var _ slog.Handler = (*MemorySink)(nil)
+
+// MemorySink 在环形缓冲区中保存最近的日志，可用于单元测试断言(见log/logtest)，
+// 也可以挂载到管理端口查看最近的日志，e.g.
+//
+//	mem := NewMemorySink(1000)
+//	logger := NewSLogger(WithOutFile("/path/to/app.log"), WithSinkHandler(mem))
+//	http.Handle("/debug/logs", mem)
+type MemorySink struct {
+	core handlerCore
+	ring *memoryRing
+}
+
+type memoryRing struct {
+	mu      sync.Mutex
+	records []MemoryRecord
+	head    int
+	size    int
+}
+
+func NewMemorySink(capacity int) *MemorySink {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemorySink{
+		core: handlerCore{opts: &slog.HandlerOptions{AddSource: true, Level: slog.Level(math.MinInt)}},
+		ring: &memoryRing{records: make([]MemoryRecord, capacity)},
+	}
+}
+
+func (m *MemorySink) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (m *MemorySink) Handle(_ context.Context, r slog.Record) error {
+	rec := MemoryRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Source:  m.core.source(r),
+	}
+	flattenAttrs("", m.core.attrs(r), func(key string, v slog.Value) {
+		rec.Attrs = append(rec.Attrs, slog.Attr{Key: key, Value: v})
+	})
+
+	ring := m.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.records[(ring.head+ring.size)%len(ring.records)] = rec
+	if ring.size < len(ring.records) {
+		ring.size++
+	} else {
+		ring.head = (ring.head + 1) % len(ring.records)
+	}
+	return nil
+}
+
+func (m *MemorySink) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &MemorySink{core: m.core.withAttrs(attrs), ring: m.ring}
+}
+
+func (m *MemorySink) WithGroup(name string) slog.Handler {
+	return &MemorySink{core: m.core.withGroup(name), ring: m.ring}
+}
+
+// Records 按时间顺序返回缓冲区中的日志
+func (m *MemorySink) Records() []MemoryRecord {
+	ring := m.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	records := make([]MemoryRecord, ring.size)
+	for i := range records {
+		records[i] = ring.records[(ring.head+i)%len(ring.records)]
+	}
+	return records
+}
+
+// HasRecord 缓冲区中存在匹配的日志，匹配规则见 MemoryRecord.Match
+func (m *MemorySink) HasRecord(level slog.Level, msg string, attrs ...slog.Attr) bool {
+	for _, r := range m.Records() {
+		if r.Match(level, msg, attrs...) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemorySink) Reset() {
+	ring := m.ring
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	clear(ring.records)
+	ring.head, ring.size = 0, 0
+}
+
+type memoryRecordPayload struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Source  string         `json:"source,omitempty"`
+	Message string         `json:"msg"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// ServeHTTP 以logfmt格式逐行返回最近的日志
+// 参数: n 返回最近的条数; level 最低级别; format=json 返回JSON数组
+func (m *MemorySink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	minLevel := slog.Level(math.MinInt)
+	if s := query.Get("level"); s != "" {
+		level, err := ParseLevel(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minLevel = level
+	}
+
+	records := make([]MemoryRecord, 0)
+	for _, rec := range m.Records() {
+		if rec.Level >= minLevel {
+			records = append(records, rec)
+		}
+	}
+	if s := query.Get("n"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid n: "+s, http.StatusBadRequest)
+			return
+		}
+		records = records[max(len(records)-n, 0):]
+	}
+
+	if query.Get("format") == "json" {
+		payload := make([]memoryRecordPayload, 0, len(records))
+		for _, rec := range records {
+			p := memoryRecordPayload{Time: rec.Time, Level: LevelName(rec.Level), Source: rec.sourceString(), Message: rec.Message}
+			if len(rec.Attrs) > 0 {
+				p.Attrs = make(map[string]any, len(rec.Attrs))
+				for _, a := range rec.Attrs {
+					p.Attrs[a.Key] = a.Value.Any()
+				}
+			}
+			payload = append(payload, p)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(payload)
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range records {
+		rec.appendLogfmt(&buf)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (r MemoryRecord) sourceString() string {
+	if r.Source == nil {
+		return ""
+	}
+	return r.Source.File + ":" + strconv.Itoa(r.Source.Line)
+}
+
+func (r MemoryRecord) appendLogfmt(buf *bytes.Buffer) {
+	field := func(key string, v slog.Value) {
+		if key != slog.TimeKey {
+			buf.WriteByte(' ')
+		}
+		appendLogfmtKey(buf, key)
+		buf.WriteByte('=')
+		appendLogfmtValue(buf, formatValue(v, defaultTimeFormat(formatLogfmt)))
+	}
+
+	field(slog.TimeKey, slog.TimeValue(r.Time))
+	field(slog.LevelKey, slog.StringValue(LevelName(r.Level)))
+	if src := r.sourceString(); src != "" {
+		field(slog.SourceKey, slog.StringValue(src))
+	}
+	field(slog.MessageKey, slog.StringValue(r.Message))
+	for _, a := range r.Attrs {
+		field(a.Key, a.Value)
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/BabySid/gobase"
+)
+
+const (
+	LoggerKey = "logger"
+
+	defaultModule = "*"
+)
+
+type moduleRule struct {
+	prefix string
+	level  slog.Level
+}
+
+// moduleLevels 按Logger名字前缀覆盖日志级别，未匹配的Logger使用全局level
+type moduleLevels struct {
+	rules atomic.Pointer[[]moduleRule]
+}
+
+// parseModuleLevels 解析形如 "log_sub=trace,http.*=debug,*=info" 的配置
+// 前缀匹配以"."分隔的名字，"log_sub" 同时匹配 "log_sub" 和 "log_sub.reader"
+// 以"*"结尾时按字符串前缀匹配，单独的"*"表示全局level
+func parseModuleLevels(spec string) ([]moduleRule, *slog.Level, error) {
+	var rules []moduleRule
+	var def *slog.Level
+	for _, item := range gobase.SplitAndTrimSpace(spec, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, nil, fmt.Errorf("invalid module level:%s", item)
+		}
+		name := strings.TrimSpace(kv[0])
+		level, err := ParseLevel(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, nil, err
+		}
+		if name == defaultModule {
+			def = &level
+			continue
+		}
+		if name == "" {
+			return nil, nil, fmt.Errorf("invalid module level:%s", item)
+		}
+		rules = append(rules, moduleRule{prefix: name, level: level})
+	}
+
+	// 最长前缀优先
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].prefix) > len(rules[j].prefix)
+	})
+	return rules, def, nil
+}
+
+func (m *moduleLevels) set(rules []moduleRule) {
+	m.rules.Store(&rules)
+}
+
+func (m *moduleLevels) levelFor(name string) (slog.Level, bool) {
+	if name == "" {
+		return 0, false
+	}
+	rules := m.rules.Load()
+	if rules == nil {
+		return 0, false
+	}
+	for _, r := range *rules {
+		if matchModule(r.prefix, name) {
+			return r.level, true
+		}
+	}
+	return 0, false
+}
+
+func matchModule(prefix string, name string) bool {
+	if p, ok := strings.CutSuffix(prefix, "*"); ok {
+		return strings.HasPrefix(name, p)
+	}
+	return name == prefix || strings.HasPrefix(name, prefix+".")
+}
+
+// WithModuleLevels 按Logger名字设置日志级别, e.g. "log_sub=trace,*=info"
+func WithModuleLevels(spec string) Option {
+	return func(opt *option) {
+		rules, def, err := parseModuleLevels(spec)
+		if err != nil {
+			panic(err)
+		}
+		opt.modules.set(rules)
+		if def != nil {
+			opt.level.Set(*def)
+		}
+	}
+}
+
+// SetModuleLevels 运行时替换所有按名字设置的日志级别，对所有派生的Logger生效
+func (d *SLogger) SetModuleLevels(spec string) error {
+	rules, def, err := parseModuleLevels(spec)
+	if err != nil {
+		return err
+	}
+	d.opt.modules.set(rules)
+	if def != nil {
+		d.opt.level.Set(*def)
+	}
+	return nil
+}
+
+// Named 返回名为name的子Logger，名字以 logger=name 属性输出，嵌套调用时以"."连接
+func (d *SLogger) Named(name string) ExtLogger {
+	if name == "" {
+		return d
+	}
+
+	n := d.clone()
+	n.out = slog.New(n.out.Handler().(*logHandler).named(name))
+	n.err = slog.New(n.err.Handler().(*logHandler).named(name))
+	return n
+}
@@ -0,0 +1,145 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/muesli/termenv"
+)
+
+var _ slog.Handler = (*prettyJSONHandler)(nil)
+
+// prettyJSONHandler 将JSONHandler的输出按缩进格式着色，便于本地开发时阅读
+// 每条记录占多行，不适合需要按行解析的场景
+type prettyJSONHandler struct {
+	slog.Handler
+	colorizer colorizer
+
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+	out io.Writer
+}
+
+func newPrettyJSONHandler(out io.Writer, cz colorizer, opts *slog.HandlerOptions) *prettyJSONHandler {
+	buf := &bytes.Buffer{}
+	return &prettyJSONHandler{
+		Handler:   slog.NewJSONHandler(buf, opts),
+		colorizer: cz,
+		mu:        &sync.Mutex{},
+		buf:       buf,
+		out:       out,
+	}
+}
+
+func (h *prettyJSONHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.Handler.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(h.buf.Bytes()), "", "  "); err != nil {
+		return err
+	}
+	_, err := h.out.Write(h.colorize(indented.Bytes(), r.Level))
+	return err
+}
+
+// colorize 顶层的time/level/source/msg按对应颜色输出，其余key和value分别使用AttrKey和AttrValue
+func (h *prettyJSONHandler) colorize(data []byte, level slog.Level) []byte {
+	p := h.colorizer.palette
+	var out bytes.Buffer
+	depth := 0
+	topKey := ""
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for j < len(data) && data[j] != '"' {
+				if data[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j++
+			s := string(data[i:j])
+			k := j
+			for k < len(data) && data[k] == ' ' {
+				k++
+			}
+			if k < len(data) && data[k] == ':' {
+				if depth == 1 {
+					topKey = s[1 : len(s)-1]
+				}
+				out.WriteString(h.colorizer.paint(s, p.AttrKey))
+			} else {
+				out.WriteString(h.colorizer.paint(s, h.valueColor(depth, topKey, level)))
+			}
+			i = j
+		case c == '{' || c == '[':
+			depth++
+			out.WriteByte(c)
+			i++
+		case c == '}' || c == ']':
+			depth--
+			out.WriteByte(c)
+			i++
+		case c == ',' || c == ':' || c == ' ' || c == '\n':
+			out.WriteByte(c)
+			i++
+		default:
+			// 数字、true/false/null
+			j := i
+			for j < len(data) && strings.IndexByte(",:]} \n", data[j]) < 0 {
+				j++
+			}
+			out.WriteString(h.colorizer.paint(string(data[i:j]), h.valueColor(depth, topKey, level)))
+			i = j
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+func (h *prettyJSONHandler) valueColor(depth int, topKey string, level slog.Level) termenv.Color {
+	p := h.colorizer.palette
+	switch topKey {
+	case slog.TimeKey:
+		if depth == 1 {
+			return p.Time
+		}
+	case slog.LevelKey:
+		if depth == 1 {
+			return p.level(level)
+		}
+	case slog.MessageKey:
+		if depth == 1 {
+			return p.Message
+		}
+	case slog.SourceKey:
+		return p.Source
+	}
+	return p.AttrValue
+}
+
+func (h *prettyJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.Handler = h.Handler.WithAttrs(attrs)
+	return &n
+}
+
+func (h *prettyJSONHandler) WithGroup(name string) slog.Handler {
+	n := *h
+	n.Handler = h.Handler.WithGroup(name)
+	return &n
+}
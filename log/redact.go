@@ -0,0 +1,238 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"log/slog"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BabySid/gobase"
+)
+
+const (
+	DefaultRedactMask = "******"
+
+	maxRedactDepth = 8
+)
+
+var (
+	// CreditCardPattern 13-19位的卡号，允许以空格或"-"分隔
+	CreditCardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	// BearerTokenPattern e.g. Authorization: Bearer eyJhbGciOi...
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`)
+)
+
+type RedactConfig struct {
+	// Keys 属性名，忽略大小写，e.g. password
+	Keys []string
+	// Globs 匹配属性名或以"."连接的完整路径，忽略大小写，e.g. *token*, req.headers.*
+	Globs []string
+	// Values 字符串值(包括msg)中匹配的部分被替换，e.g. CreditCardPattern, BearerTokenPattern
+	Values []*regexp.Regexp
+	// Mask 默认为 DefaultRedactMask
+	Mask string
+}
+
+// WithRedaction 对所有sink生效，递归处理分组、LogValuer的结果以及slog.Any中的struct/map/slice
+// struct/map中存在需要屏蔽的字段时，以map[string]any输出(字段名优先使用json tag)，否则保持原值
+func WithRedaction(cfg RedactConfig) Option {
+	return func(opt *option) {
+		for _, g := range cfg.Globs {
+			_, err := path.Match(strings.ToLower(g), "")
+			gobase.TrueF(err == nil, "invalid redaction glob %q. err=%v", g, err)
+		}
+		opt.redactor = newRedactor(cfg)
+	}
+}
+
+type redactor struct {
+	keys   map[string]struct{}
+	globs  []string
+	values []*regexp.Regexp
+	mask   string
+}
+
+func newRedactor(cfg RedactConfig) *redactor {
+	r := &redactor{
+		keys:   make(map[string]struct{}, len(cfg.Keys)),
+		values: cfg.Values,
+		mask:   cfg.Mask,
+	}
+	for _, k := range cfg.Keys {
+		r.keys[strings.ToLower(k)] = struct{}{}
+	}
+	for _, g := range cfg.Globs {
+		r.globs = append(r.globs, strings.ToLower(g))
+	}
+	if r.mask == "" {
+		r.mask = DefaultRedactMask
+	}
+	return r
+}
+
+// record prefix为WithGroup产生的分组路径
+func (r *redactor) record(prefix string, rec slog.Record) slog.Record {
+	nr := slog.NewRecord(rec.Time, rec.Level, r.string(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(r.attr(prefix, a))
+		return true
+	})
+	return nr
+}
+
+func (r *redactor) attrs(prefix string, attrs []slog.Attr) []slog.Attr {
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		res[i] = r.attr(prefix, a)
+	}
+	return res
+}
+
+func (r *redactor) attr(prefix string, a slog.Attr) slog.Attr {
+	p := joinKey(prefix, a.Key)
+	if a.Key != "" && r.matchKey(a.Key, p) {
+		return slog.String(a.Key, r.mask)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		// 空key的分组会被展开到上一级
+		if a.Key == "" {
+			p = prefix
+		}
+		v = slog.GroupValue(r.attrs(p, v.Group())...)
+	case slog.KindString:
+		v = slog.StringValue(r.string(v.String()))
+	case slog.KindAny:
+		if nv, ok := r.any(p, v.Any(), 0); ok {
+			v = slog.AnyValue(nv)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func (r *redactor) matchKey(key string, fullPath string) bool {
+	key = strings.ToLower(key)
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+	fullPath = strings.ToLower(fullPath)
+	for _, g := range r.globs {
+		if ok, _ := path.Match(g, key); ok {
+			return true
+		}
+		if ok, _ := path.Match(g, fullPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) string(s string) string {
+	for _, re := range r.values {
+		s = re.ReplaceAllLiteralString(s, r.mask)
+	}
+	return s
+}
+
+// any 返回是否有内容被屏蔽，没有时调用方保持原值，不改变输出格式
+func (r *redactor) any(prefix string, x any, depth int) (any, bool) {
+	if x == nil || depth >= maxRedactDepth {
+		return x, false
+	}
+
+	switch v := x.(type) {
+	case string:
+		s := r.string(v)
+		return s, s != v
+	case json.Marshaler, encoding.TextMarshaler:
+		// 自定义了编码方式的类型(e.g. time.Time)不展开
+		return x, false
+	case error:
+		s := r.string(v.Error())
+		return s, s != v.Error()
+	}
+
+	rv := reflect.ValueOf(x)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return x, false
+		}
+		rv = rv.Elem()
+	}
+
+	changed := false
+	field := func(key string, fv reflect.Value) any {
+		p := joinKey(prefix, key)
+		if r.matchKey(key, p) {
+			changed = true
+			return r.mask
+		}
+		if !fv.CanInterface() {
+			return nil
+		}
+		nv, ok := r.any(p, fv.Interface(), depth+1)
+		changed = changed || ok
+		return nv
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		s := r.string(rv.String())
+		return s, s != rv.String()
+	case reflect.Struct:
+		t := rv.Type()
+		m := make(map[string]any, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			key := sf.Name
+			if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				key = tag
+			}
+			m[key] = field(key, rv.Field(i))
+		}
+		return m, changed
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return x, false
+		}
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			m[key] = field(key, iter.Value())
+		}
+		return m, changed
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return x, false
+		}
+		s := make([]any, rv.Len())
+		for i := range s {
+			nv, ok := r.any(prefix, rv.Index(i).Interface(), depth+1)
+			changed = changed || ok
+			s[i] = nv
+		}
+		return s, changed
+	}
+	return x, false
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
+}
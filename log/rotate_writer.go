@@ -0,0 +1,327 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/strftime"
+)
+
+var _ io.WriteCloser = (*rotateWriter)(nil)
+
+// rotateWriter 支持按大小切分，可与按时间切分组合使用
+// 仅按大小: app.log -> app.log.1, app.log.2 ...
+// 时间+大小: app.log.2025061014 -> app.log.2025061014.1 ...，app.log 为指向当前文件的软链
+// 开启压缩后，切分出的文件在后台压缩为 .gz/.zst，保留策略同样作用于压缩文件
+type rotateWriter struct {
+	mu sync.Mutex
+
+	linkName string
+	byTime   *rotateByTime
+	pattern  *strftime.Strftime
+	glob     string
+	bySize   *rotateBySize
+	maxCount int
+
+	compress   Compression
+	compressCh chan compressJob
+	// compressDone 压缩协程退出后关闭
+	compressDone chan struct{}
+	// 压缩过程与按大小切分的重命名、清理互斥，避免压缩中的文件被挪走或删除
+	compressMu sync.Mutex
+
+	file    *os.File
+	curName string
+	size    int64
+	closed  bool
+}
+
+var strftimeVerbRe = regexp.MustCompile(`%[%+A-Za-z]`)
+
+func newRotateWriter(file string, opt *option) (*rotateWriter, error) {
+	byTime := opt.rotateByTime
+	w := &rotateWriter{
+		linkName: file,
+		byTime:   byTime,
+		glob:     file + ".*",
+		bySize:   opt.rotateBySize,
+		maxCount: opt.maxCount,
+		compress: opt.compress,
+	}
+
+	if byTime != nil {
+		p, err := strftime.New(file + byTime.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid strftime pattern: %w", err)
+		}
+		w.pattern = p
+		w.glob = strftimeVerbRe.ReplaceAllString(file+byTime.pattern, "*") + "*"
+	}
+
+	if err := w.openFile(w.genFilename()); err != nil {
+		return nil, err
+	}
+	// 压缩上次运行时未来得及压缩的文件
+	w.rotated()
+	return w, nil
+}
+
+func (w *rotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// 关闭后不再重新打开文件及启动压缩协程
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if name := w.genFilename(); name != w.curName {
+		if err = w.openFile(name); err != nil {
+			return 0, err
+		}
+		w.rotated()
+	}
+
+	if w.bySize != nil && w.size > 0 && w.size+int64(len(p)) > w.bySize.maxBytes {
+		if err = w.rotateBySize(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if w.compressCh != nil {
+		// 等待压缩完成，避免进程退出时留下不完整的压缩文件
+		close(w.compressCh)
+		<-w.compressDone
+		w.compressCh = nil
+	}
+	return err
+}
+
+func (w *rotateWriter) genFilename() string {
+	if w.pattern == nil {
+		return w.linkName
+	}
+
+	// 与rotatelogs保持一致，按本地时间截断
+	now := time.Now()
+	base := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), time.UTC)
+	base = base.Truncate(w.byTime.rotateTime)
+	base = time.Date(base.Year(), base.Month(), base.Day(), base.Hour(), base.Minute(), base.Second(), base.Nanosecond(), now.Location())
+	return w.pattern.FormatString(base)
+}
+
+func (w *rotateWriter) openFile(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if w.file != nil {
+		_ = w.file.Close()
+	}
+	w.file = f
+	w.curName = name
+	w.size = fi.Size()
+
+	if name != w.linkName {
+		return w.link(name)
+	}
+	return nil
+}
+
+func (w *rotateWriter) link(name string) error {
+	dest := name
+	if rel, err := filepath.Rel(filepath.Dir(w.linkName), name); err == nil {
+		dest = rel
+	}
+
+	tmp := name + "_symlink"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(dest, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.linkName)
+}
+
+// rotateBySize 依次将 name.N-1 重命名为 name.N，超出 maxBackups 的备份被删除
+func (w *rotateWriter) rotateBySize() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	name := w.curName
+	if w.bySize.maxBackups <= 0 {
+		_ = os.Remove(name)
+		return w.openFile(name)
+	}
+
+	w.compressMu.Lock()
+	ext := w.compress.Ext()
+	_ = os.Remove(backupName(name, w.bySize.maxBackups))
+	_ = os.Remove(backupName(name, w.bySize.maxBackups) + ext)
+	for i := w.bySize.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(backupName(name, i), backupName(name, i+1))
+		if ext != "" {
+			_ = os.Rename(backupName(name, i)+ext, backupName(name, i+1)+ext)
+		}
+	}
+	err := os.Rename(name, backupName(name, 1))
+	w.compressMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err = w.openFile(name); err != nil {
+		return err
+	}
+	w.rotated()
+	return nil
+}
+
+func backupName(name string, i int) string {
+	return fmt.Sprintf("%s.%d", name, i)
+}
+
+// compressJob 切分时所有尚未压缩的文件
+type compressJob struct {
+	files []string
+}
+
+// rotated 在文件被切分出去后调用，负责压缩及清理，调用方持有w.mu
+func (w *rotateWriter) rotated() {
+	if w.compress != CompressNone {
+		// 每次都重新查找未压缩的文件，之前失败或未处理的文件会在下次切分时一并压缩
+		if files := w.uncompressed(); len(files) > 0 {
+			if w.compressCh == nil {
+				w.compressCh = make(chan compressJob, 16)
+				w.compressDone = make(chan struct{})
+				go w.runCompress(w.compressCh)
+			}
+			// 压缩跟不上时阻塞写入，而不是丢弃任务
+			w.compressCh <- compressJob{files: files}
+		}
+	}
+	w.purge()
+}
+
+// uncompressed 返回已切分出去且尚未压缩的文件
+func (w *rotateWriter) uncompressed() []string {
+	matches, err := filepath.Glob(w.glob)
+	if err != nil {
+		return nil
+	}
+
+	files := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if path == w.linkName || path == w.curName || filepath.Ext(path) == ".tmp" || isCompressed(path) {
+			continue
+		}
+		fi, err := os.Lstat(path)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, path)
+	}
+	return files
+}
+
+func isCompressed(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range CompressExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *rotateWriter) runCompress(ch <-chan compressJob) {
+	defer close(w.compressDone)
+	for job := range ch {
+		w.compressMu.Lock()
+		for _, name := range job.files {
+			// 可能已被之前的任务压缩，或已被按大小切分重命名
+			if _, err := os.Stat(name); err == nil {
+				_ = compressFile(name, w.compress)
+			}
+		}
+		w.compressMu.Unlock()
+	}
+}
+
+func (w *rotateWriter) purge() {
+	var maxAge time.Duration
+	if w.byTime != nil {
+		maxAge = w.byTime.maxAge
+	}
+	if maxAge <= 0 && w.maxCount <= 0 {
+		return
+	}
+
+	// 与压缩互斥，避免删除正在压缩的文件
+	w.compressMu.Lock()
+	defer w.compressMu.Unlock()
+
+	matches, err := filepath.Glob(w.glob)
+	if err != nil {
+		return
+	}
+
+	type rotatedFile struct {
+		path    string
+		modTime time.Time
+	}
+	files := make([]rotatedFile, 0, len(matches))
+	for _, path := range matches {
+		if path == w.linkName || path == w.curName || filepath.Ext(path) == ".tmp" {
+			continue
+		}
+		fi, err := os.Lstat(path)
+		if err != nil || fi.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		files = append(files, rotatedFile{path: path, modTime: fi.ModTime()})
+	}
+
+	// 新文件在前
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	cutoff := time.Now().Add(-maxAge)
+	for i, f := range files {
+		if (w.maxCount > 0 && i >= w.maxCount) || (maxAge > 0 && f.modTime.Before(cutoff)) {
+			_ = os.Remove(f.path)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateWriterWriteAfterClose(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	w, err := newRotateWriter(file, &option{rotateBySize: &rotateBySize{maxBytes: 1, maxBackups: 3}, compress: CompressGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte("a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = w.Write([]byte("b\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("write after close err=%v, want %v", err, os.ErrClosed)
+	}
+	if w.file != nil || w.compressCh != nil {
+		t.Error("write after close reopened the writer")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "a\n" {
+		t.Errorf("unexpected file content %q", data)
+	}
+}
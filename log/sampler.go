@@ -0,0 +1,129 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	SampledMsgKey     = "sampled_msg"
+	SuppressedKey     = "suppressed"
+	maxSamplerKeys    = 4096
+	samplerSummaryMsg = "log records suppressed by sampling"
+)
+
+type samplerKey struct {
+	level slog.Level
+	msg   string
+}
+
+type sampleCounter struct {
+	resetAt time.Time
+	n       int
+	dropped int
+	// window 每个窗口递增，避免上个窗口的定时器输出当前窗口的计数
+	window int
+}
+
+// sampler 参考zap的采样策略：每个(level, msg)在每个tick内先输出first条，之后每thereafter条输出1条
+// 窗口结束时若有被丢弃的日志，输出一条汇总日志
+type sampler struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+
+	mu       sync.Mutex
+	counters map[samplerKey]*sampleCounter
+}
+
+func newSampler(tick time.Duration, first int, thereafter int) *sampler {
+	return &sampler{
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+		counters:   make(map[samplerKey]*sampleCounter),
+	}
+}
+
+// sample 返回该记录是否需要输出，emit用于窗口结束时输出汇总日志
+func (s *sampler) sample(r *slog.Record, emit func(slog.Record)) bool {
+	key := samplerKey{level: r.Level, msg: r.Message}
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	s.mu.Lock()
+	c, ok := s.counters[key]
+	// 上个窗口的定时器尚未触发时，在切换窗口前输出其汇总
+	prevDropped := 0
+	if !ok || !now.Before(c.resetAt) {
+		if !ok {
+			if len(s.counters) >= maxSamplerKeys {
+				s.sweep(now)
+			}
+			c = &sampleCounter{}
+			s.counters[key] = c
+		}
+		prevDropped = c.dropped
+		c.resetAt = now.Add(s.tick)
+		c.n = 0
+		c.dropped = 0
+		c.window++
+	}
+
+	c.n++
+	keep := c.n <= s.first || (s.thereafter > 0 && (c.n-s.first)%s.thereafter == 0)
+	if !keep {
+		c.dropped++
+		if c.dropped == 1 {
+			window := c.window
+			time.AfterFunc(c.resetAt.Sub(now), func() {
+				s.summary(key, c, window, emit)
+			})
+		}
+	}
+	s.mu.Unlock()
+
+	if prevDropped > 0 {
+		emit(summaryRecord(key, prevDropped))
+	}
+	return keep
+}
+
+func (s *sampler) summary(key samplerKey, c *sampleCounter, window int, emit func(slog.Record)) {
+	s.mu.Lock()
+	dropped := 0
+	if c.window == window {
+		dropped = c.dropped
+		c.dropped = 0
+	}
+	s.mu.Unlock()
+
+	if dropped == 0 {
+		return
+	}
+	emit(summaryRecord(key, dropped))
+}
+
+func summaryRecord(key samplerKey, dropped int) slog.Record {
+	r := slog.NewRecord(time.Now(), key.level, samplerSummaryMsg, 0)
+	r.AddAttrs(slog.String(SampledMsgKey, key.msg), slog.Int(SuppressedKey, dropped))
+	return r
+}
+
+// sweep 清理已过期且没有待输出汇总的计数器，避免msg过多时内存无限增长
+func (s *sampler) sweep(now time.Time) {
+	for k, c := range s.counters {
+		if !now.Before(c.resetAt) && c.dropped == 0 {
+			delete(s.counters, k)
+		}
+	}
+}
+
+// emitSummary 与其他日志一样经过脱敏、hook及附加Logger名字等处理，但不再采样
+func (h *logHandler) emitSummary(r slog.Record) {
+	_ = h.handle(context.Background(), r)
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSamplerWindowRollover(t *testing.T) {
+	s := newSampler(time.Hour, 1, 0)
+	var emitted []slog.Record
+	emit := func(r slog.Record) {
+		emitted = append(emitted, r)
+	}
+
+	base := time.Now()
+	sample := func(at time.Time) bool {
+		r := slog.NewRecord(at, LevelInfo, "msg", 0)
+		return s.sample(&r, emit)
+	}
+
+	for i, want := range []bool{true, false, false} {
+		if got := sample(base); got != want {
+			t.Fatalf("sample #%d=%v, want %v", i, got, want)
+		}
+	}
+	// 定时器触发前进入下一个窗口，上个窗口的计数立即输出
+	if !sample(base.Add(time.Hour)) || sample(base.Add(time.Hour)) {
+		t.Fatal("unexpected sample result in the next window")
+	}
+	if len(emitted) != 1 {
+		t.Fatalf("emitted=%d, want 1", len(emitted))
+	}
+	if !(MemoryRecord{Level: emitted[0].Level, Message: emitted[0].Message, Attrs: recordAttrs(emitted[0])}).
+		Match(LevelInfo, samplerSummaryMsg, slog.String(SampledMsgKey, "msg"), slog.Int(SuppressedKey, 2)) {
+		t.Errorf("unexpected summary %v", emitted[0])
+	}
+
+	// 上个窗口的定时器不输出当前窗口的计数
+	c := s.counters[samplerKey{level: LevelInfo, msg: "msg"}]
+	s.summary(samplerKey{level: LevelInfo, msg: "msg"}, c, c.window-1, emit)
+	if len(emitted) != 1 || c.dropped != 1 {
+		t.Errorf("stale timer emitted the current window. emitted=%d dropped=%d", len(emitted), c.dropped)
+	}
+}
+
+func TestSamplerSummaryPipeline(t *testing.T) {
+	sink := NewMemorySink(100)
+	l := NewSLogger(
+		WithSinkHandler(sink),
+		WithSampling(20*time.Millisecond, 1, 0),
+		WithRedaction(RedactConfig{Values: []*regexp.Regexp{regexp.MustCompile(`secret`)}}),
+	)
+
+	var mu sync.Mutex
+	var hooked []string
+	l.AddHook(LevelInfo, func(_ context.Context, r slog.Record) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, r.Message)
+	})
+
+	named := l.Named("svc")
+	for i := 0; i < 3; i++ {
+		named.Info("token secret")
+	}
+
+	summary := []slog.Attr{
+		slog.String(LoggerKey, "svc"),
+		slog.String(SampledMsgKey, "token "+DefaultRedactMask),
+		slog.Int(SuppressedKey, 2),
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !sink.HasRecord(LevelInfo, samplerSummaryMsg, summary...) {
+		if time.Now().After(deadline) {
+			t.Fatalf("summary not found in %+v", sink.Records())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hooked) != 2 || hooked[1] != samplerSummaryMsg {
+		t.Errorf("hooked=%q, want the record and its summary", hooked)
+	}
+}
+
+func recordAttrs(r slog.Record) []slog.Attr {
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
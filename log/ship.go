@@ -0,0 +1,502 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	shipSpoolName = "ship.spool"
+)
+
+var errSpoolFull = errors.New("ship spool is full")
+
+type ShipConfig struct {
+	// Network 可选 tcp、http
+	// tcp时Addr为host:port，按行发送JSON，可对接fluent-bit/fluentd的tcp输入(format json)
+	// http时Addr为完整URL，以POST发送 application/x-ndjson，返回非2xx视为失败
+	// 记录至少发送一次：tcp写入中断时，未完整写入的记录会重新发送，收集端可能收到一条不完整的行
+	Network string
+	Addr    string
+	Header  http.Header // http请求附加的header
+
+	BatchSize     int           // 每批最多的记录数，默认100
+	FlushInterval time.Duration // 不满一批时的发送间隔，默认1s
+	QueueSize     int           // 内存队列长度，队列满时丢弃新记录，默认10000
+	Timeout       time.Duration // 连接及发送超时，默认5s
+
+	// SpoolDir 不为空时，发送失败的记录写入该目录下的spool文件，连接恢复后优先补发，进程重启后同样会补发
+	// 补发时每次发送一批，期间继续处理内存队列，新的记录先追加到spool以保证顺序
+	// 为空时发送失败的记录直接丢弃
+	SpoolDir      string
+	SpoolMaxBytes int64 // spool中待补发数据的大小上限，超出后丢弃，默认64MB
+
+	// 发送失败后按指数退避重连，退避期间的记录直接写入spool
+	MinBackoff time.Duration // 默认100ms
+	MaxBackoff time.Duration // 默认30s
+}
+
+type ShipStats struct {
+	QueueDepth int    // 内存队列中待发送的记录数
+	SpoolBytes int64  // spool文件中待补发的字节数
+	Sent       uint64 // 已发送的记录数
+	Spooled    uint64 // 写入spool的记录数
+	Dropped    uint64 // 因队列满、spool超限或关闭后写入而丢弃的记录数
+}
+
+var _ slog.Handler = (*ShipHandler)(nil)
+
+// ShipHandler 将JSON格式的日志批量发送到远端收集器，不阻塞写日志的协程
+// 通过 WithSinkHandler 接入SLogger，SLogger.Close时写完队列并关闭
+type ShipHandler struct {
+	slog.Handler
+	s *shipper
+}
+
+func NewShipHandler(cfg ShipConfig) (*ShipHandler, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("ship addr must be set")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.SpoolMaxBytes <= 0 {
+		cfg.SpoolMaxBytes = 64 << 20
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(30*time.Second, cfg.MinBackoff)
+	}
+
+	var transport shipTransport
+	switch cfg.Network {
+	case "tcp", "tcp4", "tcp6":
+		transport = &tcpShipTransport{network: cfg.Network, addr: cfg.Addr, timeout: cfg.Timeout}
+	case "http":
+		transport = &httpShipTransport{url: cfg.Addr, header: cfg.Header, client: &http.Client{Timeout: cfg.Timeout}}
+	default:
+		return nil, fmt.Errorf("invalid ship network %q", cfg.Network)
+	}
+
+	s := &shipper{
+		cfg:       cfg,
+		transport: transport,
+		queue:     make(chan []byte, cfg.QueueSize),
+		exited:    make(chan struct{}),
+	}
+	if cfg.SpoolDir != "" {
+		spool, err := openShipSpool(filepath.Join(cfg.SpoolDir, shipSpoolName), cfg.SpoolMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		s.spool = spool
+	}
+	go s.run()
+
+	return &ShipHandler{
+		Handler: slog.NewJSONHandler(s, &slog.HandlerOptions{
+			AddSource:   true,
+			Level:       slog.Level(math.MinInt),
+			ReplaceAttr: replaceLevelName,
+		}),
+		s: s,
+	}, nil
+}
+
+func (h *ShipHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ShipHandler{Handler: h.Handler.WithAttrs(attrs), s: h.s}
+}
+
+func (h *ShipHandler) WithGroup(name string) slog.Handler {
+	return &ShipHandler{Handler: h.Handler.WithGroup(name), s: h.s}
+}
+
+func (h *ShipHandler) Stats() ShipStats {
+	st := ShipStats{
+		QueueDepth: len(h.s.queue),
+		Sent:       h.s.sent.Load(),
+		Spooled:    h.s.spooled.Load(),
+		Dropped:    h.s.dropped.Load(),
+	}
+	if h.s.spool != nil {
+		st.SpoolBytes = h.s.spool.pending.Load()
+	}
+	return st
+}
+
+// Close 发送队列中剩余的记录，发送失败时写入spool
+func (h *ShipHandler) Close() error {
+	return h.s.close()
+}
+
+// shipper 由JSONHandler写入，每次Write为一条完整的记录
+type shipper struct {
+	cfg       ShipConfig
+	transport shipTransport
+	spool     *shipSpool
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+
+	// 只在后台协程中访问
+	backoff time.Duration
+	retryAt time.Time
+
+	sent    atomic.Uint64
+	spooled atomic.Uint64
+	dropped atomic.Uint64
+
+	exited chan struct{}
+}
+
+func (s *shipper) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return len(p), nil
+	}
+
+	select {
+	case s.queue <- bytes.Clone(p):
+	default:
+		s.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+func (s *shipper) run() {
+	defer close(s.exited)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	for {
+		var b []byte
+		ok, tick := true, false
+		select {
+		case b, ok = <-s.queue:
+		case <-ticker.C:
+			tick = true
+		default:
+			// 队列为空时补发一批spool中的记录，优先处理队列，避免补发期间队列满而丢弃
+			if s.replaying() {
+				if err := s.spool.drainBatch(s.cfg.BatchSize, s.send); err != nil {
+					s.fail()
+				}
+				continue
+			}
+			select {
+			case b, ok = <-s.queue:
+			case <-ticker.C:
+				tick = true
+			}
+		}
+
+		if !ok {
+			s.flush(batch, true)
+			return
+		}
+		if !tick {
+			batch = append(batch, b)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+		}
+		s.flush(batch, false)
+		batch = nil
+	}
+}
+
+func (s *shipper) replaying() bool {
+	return s.spool != nil && s.spool.size > 0 && !time.Now().Before(s.retryAt)
+}
+
+// flush spool中有待补发的记录时追加到spool，保证顺序；关闭时先补发全部记录
+func (s *shipper) flush(batch [][]byte, final bool) {
+	if !final && (time.Now().Before(s.retryAt) || (s.spool != nil && s.spool.size > 0)) {
+		s.spoolBatch(batch)
+		return
+	}
+
+	if s.spool != nil {
+		if err := s.spool.drain(s.cfg.BatchSize, s.send); err != nil {
+			s.fail()
+			s.spoolBatch(batch)
+			return
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+	data := bytes.Join(batch, nil)
+	if done, err := s.send(data); err != nil {
+		s.fail()
+		// 每条记录以换行结尾，只写入spool未完整发送的记录
+		s.spoolBatch(batch[bytes.Count(data[:done], []byte{'\n'}):])
+	}
+}
+
+// send 返回已完整发送的记录的字节数，失败时其后的记录需要重新发送
+func (s *shipper) send(data []byte) (int, error) {
+	w, err := s.transport.send(data)
+	done := bytes.LastIndexByte(data[:w], '\n') + 1
+	s.sent.Add(uint64(bytes.Count(data[:done], []byte{'\n'})))
+	if err != nil {
+		return done, err
+	}
+	s.backoff = 0
+	s.retryAt = time.Time{}
+	return done, nil
+}
+
+func (s *shipper) fail() {
+	s.backoff = min(max(s.backoff*2, s.cfg.MinBackoff), s.cfg.MaxBackoff)
+	s.retryAt = time.Now().Add(s.backoff)
+}
+
+func (s *shipper) spoolBatch(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	if s.spool == nil || s.spool.append(batch) != nil {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+	s.spooled.Add(uint64(len(batch)))
+}
+
+func (s *shipper) close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.exited
+	return s.transport.close()
+}
+
+// shipSpool 以NDJSON格式保存待补发的记录，全部补发后删除文件
+type shipSpool struct {
+	path     string
+	maxBytes int64
+
+	size    int64 // 文件大小
+	offset  int64 // 已补发的位置
+	pending atomic.Int64
+}
+
+func openShipSpool(path string, maxBytes int64) (*shipSpool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &shipSpool{path: path, maxBytes: maxBytes}
+	if fi, err := os.Stat(path); err == nil {
+		s.size = fi.Size()
+		s.pending.Store(s.size)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *shipSpool) append(batch [][]byte) error {
+	n := 0
+	for _, b := range batch {
+		n += len(b)
+	}
+	// 已补发的部分不计入上限，文件在全部补发后删除
+	if s.size-s.offset+int64(n) > s.maxBytes {
+		return errSpoolFull
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := f.Write(bytes.Join(batch, nil))
+	s.size += int64(w)
+	s.pending.Store(s.size - s.offset)
+	return err
+}
+
+// drain 补发全部记录
+func (s *shipSpool) drain(batchSize int, send func(data []byte) (int, error)) error {
+	for s.size > 0 {
+		if err := s.drainBatch(batchSize, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainBatch 补发最多batchSize条记录，全部补发后删除文件
+// send返回已完整发送的字节数，失败时从该位置继续补发
+func (s *shipSpool) drainBatch(batchSize int, send func(data []byte) (int, error)) error {
+	if s.size == 0 {
+		return nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	var buf bytes.Buffer
+	read := 0 // 从文件读取的字节数，不含补上的换行
+	for lines := 0; lines < batchSize; lines++ {
+		line, err := r.ReadBytes('\n')
+		read += len(line)
+		if len(line) > 0 {
+			buf.Write(line)
+			if line[len(line)-1] != '\n' {
+				// 进程异常退出时可能只写入了部分记录
+				buf.WriteByte('\n')
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if buf.Len() > 0 {
+		done, err := send(buf.Bytes())
+		if done == buf.Len() {
+			done = read
+		}
+		s.offset += int64(done)
+		s.pending.Store(s.size - s.offset)
+		if err != nil {
+			return err
+		}
+	}
+	if s.offset < s.size {
+		return nil
+	}
+
+	s.size, s.offset = 0, 0
+	s.pending.Store(0)
+	return os.Remove(s.path)
+}
+
+// shipTransport send返回已写入的字节数
+type shipTransport interface {
+	send(data []byte) (int, error)
+	close() error
+}
+
+type tcpShipTransport struct {
+	network string
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func (t *tcpShipTransport) send(data []byte) (int, error) {
+	if t.conn == nil {
+		conn, err := net.DialTimeout(t.network, t.addr, t.timeout)
+		if err != nil {
+			return 0, err
+		}
+		t.conn = conn
+	}
+
+	_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	w, err := t.conn.Write(data)
+	if err != nil {
+		if w > 0 && data[w-1] != '\n' {
+			// 尽量结束写入了一部分的行，该记录会在新的连接上重新发送
+			_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+			_, _ = t.conn.Write([]byte{'\n'})
+		}
+		_ = t.conn.Close()
+		t.conn = nil
+		return w, err
+	}
+	return w, nil
+}
+
+func (t *tcpShipTransport) close() error {
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+type httpShipTransport struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+// send 请求失败时整批重新发送
+func (t *httpShipTransport) send(data []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("ship to %s failed. status=%s", t.url, resp.Status)
+	}
+	return len(data), nil
+}
+
+func (t *httpShipTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
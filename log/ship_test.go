@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShipTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	h, err := NewShipHandler(ShipConfig{Network: "tcp", Addr: ln.Addr().String(), FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewSLogger(WithSinkHandler(h))
+
+	msgs := []string{"a", "b", "c"}
+	for _, msg := range msgs {
+		l.Warn(msg, slog.Int("n", 1))
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	sc := bufio.NewScanner(conn)
+	for _, msg := range msgs {
+		if !sc.Scan() {
+			t.Fatalf("read record failed. err=%v", sc.Err())
+		}
+		var rec map[string]any
+		if err = json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid record %q. err=%v", sc.Bytes(), err)
+		}
+		if rec[slog.MessageKey] != msg || rec[slog.LevelKey] != "WARN" || rec["n"] != 1.0 {
+			t.Errorf("unexpected record %s", sc.Bytes())
+		}
+	}
+
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if st := h.Stats(); st.Sent != uint64(len(msgs)) || st.Dropped != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestShipReplayKeepsQueue(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 放慢补发，使补发期间有新的记录写入
+		time.Sleep(3 * time.Millisecond)
+		sc := bufio.NewScanner(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for sc.Scan() {
+			var rec map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				t.Errorf("invalid record %q. err=%v", sc.Bytes(), err)
+			}
+			got = append(got, rec[slog.MessageKey].(string))
+		}
+	}))
+	defer srv.Close()
+
+	const spooled, live = 1000, 100
+	dir := t.TempDir()
+	var spool bytes.Buffer
+	for i := 0; i < spooled; i++ {
+		spool.WriteString(`{"msg":"spooled"}` + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(dir, shipSpoolName), spool.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewShipHandler(ShipConfig{
+		Network:       "http",
+		Addr:          srv.URL,
+		BatchSize:     10,
+		QueueSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+		SpoolDir:      dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewSLogger(WithSinkHandler(h))
+	for i := 0; i < live; i++ {
+		l.Info("live")
+		time.Sleep(time.Millisecond)
+	}
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if st := h.Stats(); st.Dropped != 0 || st.Sent != spooled+live || st.SpoolBytes != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != spooled+live {
+		t.Fatalf("received=%d, want %d", len(got), spooled+live)
+	}
+	for i, msg := range got {
+		if want := map[bool]string{true: "spooled", false: "live"}[i < spooled]; msg != want {
+			t.Fatalf("record #%d=%s, want %s", i, msg, want)
+		}
+	}
+}
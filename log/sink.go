@@ -0,0 +1,185 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+
+	"github.com/BabySid/gobase"
+)
+
+// sinkSpec 记录WithSink的参数，在全局Option全部生效后再创建，使sink继承全局设置
+type sinkSpec struct {
+	file    string
+	writer  io.Writer
+	handler slog.Handler
+	opts    []Option
+}
+
+// WithSink 增加一个日志输出，file 可以是 StdOut、StdErr 或文件路径
+// opts 在全局Option的基础上覆盖该sink的设置，支持:
+// WithLevel/WithMinLevel/WithMaxLevel, WithJsonFormat, WithColorful, WithTimeRotate, WithSizeRotate,
+// WithCompress, WithMaxCount, WithAsync
+// 只对Logger整体生效的Option(e.g. WithModuleLevels, WithStackTrace, WithSampling)用于sink时panic
+// e.g. 同时输出JSON到文件、彩色文本到终端、错误日志到单独文件
+//
+//	NewSLogger(
+//		WithSink("/path/to/app.log", WithJsonFormat()),
+//		WithSink(StdOut, WithColorful()),
+//		WithSink("/path/to/app.err.log", WithMinLevel(LevelError)),
+//	)
+func WithSink(file string, opts ...Option) Option {
+	return func(opt *option) {
+		opt.sinks = append(opt.sinks, sinkSpec{file: file, opts: opts})
+	}
+}
+
+// WithSinkWriter 同WithSink，输出到任意io.Writer
+func WithSinkWriter(w io.Writer, opts ...Option) Option {
+	return func(opt *option) {
+		opt.sinks = append(opt.sinks, sinkSpec{writer: w, opts: opts})
+	}
+}
+
+// WithSinkHandler 增加一个自定义slog.Handler作为日志输出，e.g. NewSyslogHandler、NewJournaldHandler
+// 仅 WithLevel/WithMinLevel/WithMaxLevel 对该sink生效，handler实现io.Closer时随SLogger.Close关闭
+func WithSinkHandler(h slog.Handler, opts ...Option) Option {
+	return func(opt *option) {
+		opt.sinks = append(opt.sinks, sinkSpec{handler: h, opts: opts})
+	}
+}
+
+// WithMinLevel 仅用于sink，低于level的日志不输出到该sink
+func WithMinLevel(level slog.Level) Option {
+	return func(opt *option) {
+		opt.minLevel = level
+	}
+}
+
+// WithMaxLevel 仅用于sink，高于level的日志不输出到该sink
+func WithMaxLevel(level slog.Level) Option {
+	return func(opt *option) {
+		opt.maxLevel = level
+	}
+}
+
+// sinkOption sink继承全局设置，但不共享可变的切片和level
+// sink的WithLevel等同于WithMinLevel，不影响全局level及其他sink
+func (opt *option) sinkOption(spec sinkSpec) option {
+	so := *opt
+	so.sinks = nil
+	so.minLevel = math.MinInt
+	so.maxLevel = math.MaxInt
+	so.level = &slog.LevelVar{}
+	so.level.Set(math.MinInt)
+	so.modules = &moduleLevels{}
+	for _, o := range spec.opts {
+		o(&so)
+	}
+
+	// 以下Option由logHandler统一处理，用于sink时不会生效
+	unsupported := func(ok bool, name string) {
+		gobase.TrueF(ok, "%s is not supported by sink, use it as a global option", name)
+	}
+	unsupported(so.modules.rules.Load() == nil, "WithModuleLevels")
+	unsupported(so.skipCaller == opt.skipCaller, "WithSkipCaller")
+	unsupported(len(so.ctxAttrs) == len(opt.ctxAttrs), "WithContextAttrs")
+	unsupported(so.sampler == opt.sampler, "WithSampling")
+	unsupported(so.stackLevel == opt.stackLevel, "WithStackTrace")
+	unsupported(so.redactor == opt.redactor, "WithRedaction")
+	unsupported(so.outFile == opt.outFile && so.errFile == opt.errFile, "WithOutFile/WithErrFile")
+	unsupported(len(so.sinks) == 0, "WithSink")
+
+	if level := so.level.Level(); level > so.minLevel {
+		so.minLevel = level
+	}
+	so.level = opt.level
+	so.modules = opt.modules
+	return so
+}
+
+// legacySinks 将WithOutFile/WithErrFile转换为sink:
+// 两者都设置时 Trace/Debug/Info 输出到outFile，Warn/Error 输出到errFile，否则所有日志输出到同一文件
+func (opt *option) legacySinks() []sinkSpec {
+	out, err := opt.outFile, opt.errFile
+	switch {
+	case out != "" && err != "" && out != err:
+		return []sinkSpec{
+			{file: out, opts: []Option{WithMaxLevel(LevelWarn - 1)}},
+			{file: err, opts: []Option{WithMinLevel(LevelWarn)}},
+		}
+	case out != "":
+		return []sinkSpec{{file: out}}
+	case err != "":
+		return []sinkSpec{{file: err}}
+	}
+	return nil
+}
+
+var _ slog.Handler = (*levelRangeHandler)(nil)
+
+// levelRangeHandler 只处理[min, max]范围内的日志
+type levelRangeHandler struct {
+	slog.Handler
+	min slog.Level
+	max slog.Level
+}
+
+func (h *levelRangeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.min && level <= h.max && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelRangeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelRangeHandler{Handler: h.Handler.WithAttrs(attrs), min: h.min, max: h.max}
+}
+
+func (h *levelRangeHandler) WithGroup(name string) slog.Handler {
+	return &levelRangeHandler{Handler: h.Handler.WithGroup(name), min: h.min, max: h.max}
+}
+
+var _ slog.Handler = (*multiHandler)(nil)
+
+// multiHandler 将日志分发到所有sink
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
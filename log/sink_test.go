@@ -0,0 +1,46 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSinkLevelIsolated(t *testing.T) {
+	var all, errs bytes.Buffer
+	l := NewSLogger(
+		WithLevel("debug"),
+		WithSinkWriter(&all),
+		WithSinkWriter(&errs, WithLevel("error")),
+	)
+
+	l.Debug("debug msg")
+	l.Error("error msg")
+
+	if l.Level() != LevelDebug {
+		t.Fatalf("global level=%v, want %v", l.Level(), LevelDebug)
+	}
+	if !strings.Contains(all.String(), "debug msg") || !strings.Contains(all.String(), "error msg") {
+		t.Errorf("unexpected output of default sink:\n%s", all.String())
+	}
+	if strings.Contains(errs.String(), "debug msg") || !strings.Contains(errs.String(), "error msg") {
+		t.Errorf("unexpected output of error sink:\n%s", errs.String())
+	}
+}
+
+func TestSinkUnsupportedOption(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"WithModuleLevels": WithModuleLevels("a=trace"),
+		"WithStackTrace":   WithStackTrace(LevelError),
+		"WithSampling":     WithSampling(1, 1, 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s in sink should panic", name)
+				}
+			}()
+			NewSLogger(WithSinkWriter(&bytes.Buffer{}, opt))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/BabySid/gobase"
+)
+
+const (
+	StackKey = "stack"
+)
+
+// WithStackTrace 不低于level的日志附加调用栈，JSON格式输出为 [{file, line, function}] 数组，文本格式在日志行后逐行缩进输出
+func WithStackTrace(level slog.Level) Option {
+	return func(opt *option) {
+		opt.stackLevel = &level
+	}
+}
+
+type stackFrame struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function"`
+}
+
+type stackTrace []gobase.CallFrame
+
+func (s stackTrace) MarshalJSON() ([]byte, error) {
+	frames := make([]stackFrame, len(s))
+	for i, f := range s {
+		frames[i] = stackFrame{File: f.File, Line: f.Line, Function: f.Function}
+	}
+	return json.Marshal(frames)
+}
+
+func (s stackTrace) String() string {
+	var buf bytes.Buffer
+	s.appendIndented(&buf)
+	return buf.String()
+}
+
+// appendIndented 与panic时的调用栈格式一致
+func (s stackTrace) appendIndented(buf *bytes.Buffer) {
+	for _, f := range s {
+		buf.WriteString("\t")
+		buf.WriteString(f.Function)
+		buf.WriteString("\n\t\t")
+		buf.WriteString(f.File)
+		buf.WriteString(":")
+		buf.WriteString(strconv.Itoa(f.Line))
+		buf.WriteString("\n")
+	}
+}
+
+var _ slog.Handler = (*stackTextHandler)(nil)
+
+// stackTextHandler 用于文本类格式，将调用栈从属性中取出，在日志行之后以缩进形式输出
+type stackTextHandler struct {
+	slog.Handler
+	mu  *sync.Mutex
+	out io.Writer
+}
+
+func newStackTextHandler(handler slog.Handler, out io.Writer) *stackTextHandler {
+	return &stackTextHandler{Handler: handler, mu: &sync.Mutex{}, out: out}
+}
+
+func (h *stackTextHandler) Handle(ctx context.Context, r slog.Record) error {
+	var stack stackTrace
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		if s, ok := a.Value.Any().(stackTrace); ok && a.Key == StackKey {
+			stack = s
+			found = true
+			return false
+		}
+		return true
+	})
+	// 没有调用栈的记录也需要加锁，避免写在其他记录的日志行与调用栈之间
+	if !found {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.Handler.Handle(ctx, r)
+	}
+
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if _, ok := a.Value.Any().(stackTrace); !ok || a.Key != StackKey {
+			nr.AddAttrs(a)
+		}
+		return true
+	})
+
+	var buf bytes.Buffer
+	stack.appendIndented(&buf)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.Handler.Handle(ctx, nr); err != nil {
+		return err
+	}
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func (h *stackTextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &stackTextHandler{Handler: h.Handler.WithAttrs(attrs), mu: h.mu, out: h.out}
+}
+
+func (h *stackTextHandler) WithGroup(name string) slog.Handler {
+	return &stackTextHandler{Handler: h.Handler.WithGroup(name), mu: h.mu, out: h.out}
+}
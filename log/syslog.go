@@ -0,0 +1,276 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslog facility
+const (
+	FacilityKern   = 0
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+	FacilityLocal7 = 23
+)
+
+// syslog severity
+const (
+	SeverityCrit    = 2
+	SeverityErr     = 3
+	SeverityWarning = 4
+	SeverityInfo    = 6
+	SeverityDebug   = 7
+)
+
+const (
+	defaultSyslogSDID = "attrs@32473"
+	syslogSourceSDID  = "source@32473"
+	syslogTimeFormat  = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SyslogSeverity 将slog level映射为syslog severity，LevelTrace与LevelDebug均为debug，LevelPanic与LevelFatal为crit
+func SyslogSeverity(level slog.Level) int {
+	switch {
+	case level > LevelError:
+		return SeverityCrit
+	case level >= LevelError:
+		return SeverityErr
+	case level >= LevelWarn:
+		return SeverityWarning
+	case level >= LevelInfo:
+		return SeverityInfo
+	default:
+		return SeverityDebug
+	}
+}
+
+type SyslogConfig struct {
+	// Network 可选 unixgram、unix、udp、tcp，为空时连接本机的 /dev/log
+	Network string
+	Addr    string
+
+	Facility int
+	AppName  string // 默认为进程名
+	Hostname string // 默认为os.Hostname()
+	// SDID 属性输出到的structured data id，默认为 attrs@32473
+	SDID string
+}
+
+var _ slog.Handler = (*SyslogHandler)(nil)
+
+// SyslogHandler 以RFC 5424格式发送日志，属性作为structured data输出
+// 流式连接(tcp/unix)使用RFC 6587的octet-counting分帧
+// 通过 WithSinkHandler 接入SLogger
+type SyslogHandler struct {
+	core handlerCore
+	cfg  SyslogConfig
+	pid  string
+
+	conn *syslogConn
+}
+
+type syslogConn struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	closed  bool
+}
+
+func NewSyslogHandler(cfg SyslogConfig) (*SyslogHandler, error) {
+	if cfg.Network == "" {
+		cfg.Network = "unixgram"
+		if cfg.Addr == "" {
+			cfg.Addr = "/dev/log"
+		}
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = defaultSyslogSDID
+	}
+
+	c := &syslogConn{network: cfg.Network, addr: cfg.Addr}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	return &SyslogHandler{
+		core: handlerCore{opts: &slog.HandlerOptions{AddSource: true}},
+		cfg:  cfg,
+		pid:  strconv.Itoa(os.Getpid()),
+		conn: c,
+	}, nil
+}
+
+func (h *SyslogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *SyslogHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	pri := h.cfg.Facility*8 + SyslogSeverity(r.Level)
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s - ",
+		pri, t.Format(syslogTimeFormat),
+		syslogHeaderField(h.cfg.Hostname, 255), syslogHeaderField(h.cfg.AppName, 48), h.pid)
+
+	sd := false
+	if src := h.core.source(r); src != nil {
+		buf.WriteString("[" + syslogSourceSDID)
+		appendSDParam(&buf, "file", src.File)
+		appendSDParam(&buf, "line", strconv.Itoa(src.Line))
+		appendSDParam(&buf, "function", src.Function)
+		buf.WriteString("]")
+		sd = true
+	}
+
+	attrs := h.core.attrs(r)
+	if len(attrs) > 0 {
+		buf.WriteString("[" + h.cfg.SDID)
+		flattenAttrs("", attrs, func(key string, v slog.Value) {
+			appendSDParam(&buf, key, formatValue(v, time.RFC3339Nano))
+		})
+		buf.WriteString("]")
+		sd = true
+	}
+	if !sd {
+		buf.WriteString("-")
+	}
+
+	buf.WriteString(" ")
+	buf.WriteString(r.Message)
+
+	return h.conn.write(buf.Bytes())
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.core = h.core.withAttrs(attrs)
+	return &n
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	n := *h
+	n.core = h.core.withGroup(name)
+	return &n
+}
+
+func (h *SyslogHandler) Close() error {
+	return h.conn.close()
+}
+
+// syslogHeaderField header中的字段只允许可见ASCII字符，空值以"-"表示
+func syslogHeaderField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// appendSDParam PARAM-NAME最长32个可见ASCII字符且不能包含 '=' ' ' ']' '"'，PARAM-VALUE需转义 '"' '\' ']'
+func appendSDParam(buf *bytes.Buffer, name string, value string) {
+	buf.WriteByte(' ')
+	n := 0
+	for i := 0; i < len(name) && n < 32; i++ {
+		c := name[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf.WriteByte(c)
+		n++
+	}
+	if n == 0 {
+		buf.WriteByte('_')
+	}
+	buf.WriteString(`="`)
+	for _, r := range value {
+		if r == '"' || r == '\\' || r == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('"')
+}
+
+func (c *syslogConn) connect() error {
+	conn, err := net.DialTimeout(c.network, c.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *syslogConn) stream() bool {
+	return c.network == "tcp" || c.network == "tcp4" || c.network == "tcp6" || c.network == "unix"
+}
+
+func (c *syslogConn) write(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 关闭后不再重连
+	if c.closed {
+		return net.ErrClosed
+	}
+	if c.stream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	var err error
+	// 写入失败时重连一次
+	for i := 0; i < 2; i++ {
+		if c.conn == nil {
+			if err = c.connect(); err != nil {
+				continue
+			}
+		}
+		if _, err = c.conn.Write(msg); err == nil {
+			return nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+func (c *syslogConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	cases := map[slog.Level]int{
+		LevelTrace: SeverityDebug,
+		LevelDebug: SeverityDebug,
+		LevelInfo:  SeverityInfo,
+		LevelWarn:  SeverityWarning,
+		LevelError: SeverityErr,
+		LevelPanic: SeverityCrit,
+		LevelFatal: SeverityCrit,
+	}
+	for level, want := range cases {
+		if got := SyslogSeverity(level); got != want {
+			t.Errorf("SyslogSeverity(%s)=%d, want %d", LevelName(level), got, want)
+		}
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram is not supported on windows")
+	}
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	h, err := NewSyslogHandler(SyslogConfig{
+		Network:  "unixgram",
+		Addr:     path,
+		Facility: FacilityLocal0,
+		AppName:  "my app",
+		Hostname: "host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewSLogger(WithSinkHandler(h))
+	defer l.Close()
+
+	l.Warn("hello world", slog.String("q", `a"b\c]d`), slog.Group("req", slog.Int("id", 7)))
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// <local0*8+warning>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [source][attrs] MSG
+	re := regexp.MustCompile(`^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host my_app \d+ - ` +
+		`\[source@32473 file="[^"]+syslog_test\.go" line="\d+" function="[^"]+"\]` +
+		`\[attrs@32473 q="a\\"b\\\\c\\]d" req\.id="7"\] hello world$`)
+	if msg := string(buf[:n]); !re.MatchString(msg) {
+		t.Errorf("unexpected syslog message:\n%s", msg)
+	}
+}
+
+func TestSyslogOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	h, err := NewSyslogHandler(SyslogConfig{Network: "tcp", Addr: ln.Addr().String(), Facility: FacilityUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msgs := []string{"first", "second\nwith newline"}
+	for _, msg := range msgs {
+		if err = h.Handle(context.Background(), slog.NewRecord(time.Now(), LevelInfo, msg, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for _, msg := range msgs {
+		size, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+		if err != nil {
+			t.Fatalf("invalid octet count %q", size)
+		}
+		frame := make([]byte, n)
+		if _, err = io.ReadFull(r, frame); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(frame, []byte("<14>1 ")) || !bytes.HasSuffix(frame, []byte(" - "+msg)) {
+			t.Errorf("unexpected frame %q", frame)
+		}
+	}
+
+	if err = h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	err = h.Handle(context.Background(), slog.NewRecord(time.Now(), LevelInfo, "after close", 0))
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write after close err=%v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestAppendSDParam(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"key", "value", ` key="value"`},
+		{"a=b c]d\"e", "x", ` a_b_c_d_e="x"`},
+		{"", "x", ` _="x"`},
+		{strings.Repeat("k", 40), "x", " " + strings.Repeat("k", 32) + `="x"`},
+		{"key", `quote" backslash\ bracket] 中文`, ` key="quote\" backslash\\ bracket\] 中文"`},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		appendSDParam(&buf, c.name, c.value)
+		if buf.String() != c.want {
+			t.Errorf("appendSDParam(%q, %q)=%s, want %s", c.name, c.value, buf.String(), c.want)
+		}
+	}
+}
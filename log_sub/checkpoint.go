@@ -0,0 +1,94 @@
+package log_sub
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/BabySid/gobase"
+)
+
+// CheckpointStore 保存Consumer已确认处理的位置，Load没有记录时返回nil
+type CheckpointStore interface {
+	Load() (*SeekInfo, error)
+	Save(info SeekInfo) error
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// FileCheckpointStore 以JSON格式保存到文件，通过gobase.WriteFile原子替换
+type FileCheckpointStore struct {
+	Path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{Path: path}
+}
+
+// NewFileCheckpointStores 用于MultiConfig.Checkpoint，每个文件的位置保存在dir下以转义后的文件路径命名的文件中
+func NewFileCheckpointStores(dir string) func(fileName string) CheckpointStore {
+	return func(fileName string) CheckpointStore {
+		return NewFileCheckpointStore(filepath.Join(dir, url.QueryEscape(fileName)+".json"))
+	}
+}
+
+func (s *FileCheckpointStore) Load() (*SeekInfo, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var info SeekInfo
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (s *FileCheckpointStore) Save(info SeekInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	return gobase.WriteFile(s.Path, data, 0644)
+}
+
+// Ack 确认line及之前的行已处理完，重启后从下一行开始读取(至少一次投递)
+// 应按接收顺序调用，配置了Checkpoint时同步保存
+func (c *Consumer) Ack(line *Line) error {
+	info := SeekInfo{
+		FileName: line.Meta.FileName,
+		Offset:   line.Meta.EndOffset,
+		Whence:   io.SeekStart,
+		Inode:    line.Meta.Inode,
+	}
+
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	c.acked = &info
+	if c.Checkpoint == nil {
+		return nil
+	}
+	return c.Checkpoint.Save(info)
+}
+
+// Acked 返回最后一次Ack的位置，没有Ack时返回nil
+func (c *Consumer) Acked() *SeekInfo {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	if c.acked == nil {
+		return nil
+	}
+	info := *c.acked
+	return &info
+}
@@ -2,12 +2,15 @@ package log_sub
 
 import (
 	"bufio"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/BabySid/gobase"
@@ -16,18 +19,31 @@ import (
 
 type LineMeta struct {
 	FileName string
+	// Offset 行首在文件中的位置，EndOffset 为下一行的行首，压缩文件为解压后的位置
+	Offset    int64
+	EndOffset int64
+	Inode     uint64
 }
 
 type Line struct {
 	Text string
 	Err  error
 	Meta LineMeta
+
+	// Fields 设置了Config.Parser时为解析结果，解析失败时为nil且ParseErr不为nil
+	Fields   map[string]any
+	ParseErr error
+
+	// consumer 产生该行的Consumer，用于MultiConsumer.Ack
+	consumer *Consumer
 }
 
 type SeekInfo struct {
 	FileName string
 	Offset   int64
 	Whence   int
+	// Inode 跟踪模式下文件的inode，恢复时据此找到被重命名的文件
+	Inode uint64
 }
 
 type DateTimeLayout struct {
@@ -46,6 +62,24 @@ func (dt *DateTimeLayout) filePath() string {
 type Config struct {
 	Location          *SeekInfo // if nil it will consumer log from cur time
 	DateTimeLogLayout *DateTimeLayout
+	// FilePath 按inode跟踪固定文件名的日志，支持 app.log -> app.log.1 的重命名滚动、删除后重新创建及copytruncate
+	// 与DateTimeLogLayout二选一，Location为nil时从文件开头读取
+	FilePath string
+
+	// Checkpoint 不为nil时保存Ack的位置，其中有记录时优先于Location
+	Checkpoint CheckpointStore
+
+	// PollInterval 不支持inotify时检查文件变化的间隔，默认1s
+	// Linux下通过inotify监听文件所在目录，文件写入、创建或重命名后立即读取
+	PollInterval time.Duration
+
+	// Multiline 不为nil时将多行合并为一个Line
+	Multiline *MultilineConfig
+	// Parser 不为nil时解析每个Line(合并多行之后)，e.g. NewJSONParser()、NewGrokParser(...)
+	Parser Parser
+
+	// ManualStart 为true时NewConsumer不开始读取，需调用Start，以便通过ctx控制生命周期
+	ManualStart bool
 
 	Logger mylog.Logger
 }
@@ -66,21 +100,79 @@ type Consumer struct {
 
 	curDateTimeLogMeta dateTimeLog
 	file               *os.File
+	fileName           string        // 不含压缩后缀的文件名
+	decoder            io.ReadCloser // 压缩文件的解压reader
 	reader             *bufio.Reader
+	notifier           *changeNotifier
+	multiline          *multiline
+
+	// 由读取协程修改，Tell在其他协程中读取
+	posMu     sync.Mutex
+	offset    int64  // 已读取的(解压后)字节数
+	lineStart int64  // 当前行的行首
+	inode     uint64 // 已打开文件的inode
+
+	ackMu sync.Mutex
+	acked *SeekInfo
+
+	runMu  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// 由MultiConsumer设置: 空闲超时后关闭文件、限制同时打开的文件数、退出时回调
+	idleTimeout time.Duration
+	sem         chan struct{}
+	semHeld     bool
+	onExit      func(err error)
 }
 
+// LoggerName Consumer日志的Logger名字，可通过 SLogger.SetModuleLevels("log_sub=trace") 单独调整级别
+const LoggerName = "log_sub"
+
 const (
 	defaultBufSize = 1024
 	maxReadSize    = 1024 * 1024
+
+	minRetryInterval = time.Second
+	maxRetryInterval = 30 * time.Second
 )
 
+// NewConsumer 创建后立即开始读取，可通过Stop停止；Config.ManualStart为true时调用Start后开始读取
 func NewConsumer(config Config) (*Consumer, error) {
 	if config.Logger == nil {
 		config.Logger = mylog.NewSLogger(mylog.WithOutFile(mylog.StdErr))
 	}
+	// 自定义的Logger实现没有Named时保持原样
+	if l, ok := config.Logger.(mylog.ExtLogger); ok {
+		config.Logger = l.Named(LoggerName)
+	}
 
-	if config.DateTimeLogLayout == nil {
-		return nil, errors.New("invalid config")
+	if config.Checkpoint != nil {
+		loc, err := config.Checkpoint.Load()
+		if err != nil {
+			return nil, err
+		}
+		if loc != nil {
+			config.Location = loc
+		}
+	}
+
+	if (config.DateTimeLogLayout == nil) == (config.FilePath == "") {
+		return nil, errors.New("invalid config: one of DateTimeLogLayout and FilePath must be set")
+	}
+	if config.Multiline != nil {
+		if err := config.Multiline.verify(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.FilePath != "" {
+		c := Consumer{
+			Lines:  make(chan *Line, defaultBufSize),
+			Config: config,
+		}
+		return c.autoStart()
 	}
 
 	now := time.Now()
@@ -91,7 +183,10 @@ func NewConsumer(config Config) (*Consumer, error) {
 			Whence:   0,
 		}
 	}
-	step := verifyLogStep(*config.DateTimeLogLayout, config.Location.FileName)
+	step, err := verifyLogStep(*config.DateTimeLogLayout, config.Location.FileName)
+	if err != nil {
+		return nil, err
+	}
 
 	meta := dateTimeLog{
 		cur:  now,
@@ -116,24 +211,88 @@ func NewConsumer(config Config) (*Consumer, error) {
 
 	c.Logger.Info("Consumer.curDateTimeLogMeta", slog.String("cur", gobase.FormatTimeStamp(meta.cur.Unix())), slog.Int("step", step))
 
-	go c.startConsume()
+	return c.autoStart()
+}
+
+func (c *Consumer) autoStart() (*Consumer, error) {
+	if !c.ManualStart {
+		if err := c.Start(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Start 在后台协程中读取，仅用于Config.ManualStart为true时，ctx结束或调用Stop后协程退出并关闭Lines
+// 打开文件失败时通过Lines返回错误并重试，其他读取错误返回后退出
+func (c *Consumer) Start(ctx context.Context) error {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
 
-	return &c, nil
+	if c.done != nil {
+		return errors.New("consumer already started")
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		defer close(c.Lines)
+		c.consume()
+	}()
+	return nil
+}
+
+// Stop 停止读取并等待协程退出，尚未输出的行可能被丢弃，可通过Acked或Tell获取停止时的位置
+func (c *Consumer) Stop() {
+	c.runMu.Lock()
+	cancel, done := c.cancel, c.done
+	c.runMu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
 }
 
-func (c *Consumer) startConsume() {
-	file := filepath.Join(
-		c.DateTimeLogLayout.filePath(),
-		c.curDateTimeLogMeta.cur.Format(c.DateTimeLogLayout.Layout))
+// Close 同Stop
+func (c *Consumer) Close() {
+	c.Stop()
+}
 
-	err := c.openFile(file)
-	gobase.TrueF(err == nil, "openFile(%s) failed. err=%v", file, err)
+// consume 读取协程，退出前输出未结束的多行记录并关闭文件
+func (c *Consumer) consume() {
+	dir := filepath.Dir(c.FilePath)
+	if c.DateTimeLogLayout != nil {
+		dir = c.DateTimeLogLayout.filePath()
+	}
+	c.notifier = newChangeNotifier(dir, c.PollInterval)
+	c.startMultiline()
+	defer func() {
+		c.stopMultiline()
+		c.notifier.close()
+		c.closeFile()
+	}()
+
+	var err error
+	if c.FilePath != "" {
+		err = c.startFollow()
+	} else {
+		err = c.startConsume()
+	}
+	c.Logger.Info("consumer exited", slog.String("fileName", c.fileName), slog.Any("err", err))
+}
+
+func (c *Consumer) startConsume() error {
+	if err := c.openWithRetry(c.DateTimeLogLayout.FormatFile(c.curDateTimeLogMeta.cur)); err != nil {
+		return err
+	}
 
 	if c.Location != nil {
-		_, err = c.file.Seek(c.Location.Offset, c.Location.Whence)
-		if err != nil {
+		if err := c.seek(c.Location.Offset, c.Location.Whence); err != nil {
 			c.sendLine("", err)
-			return
+			return err
 		}
 	}
 
@@ -148,59 +307,180 @@ func (c *Consumer) startConsume() {
 
 			nxt, err := c.waitNxtFile()
 			if err != nil {
-				c.sendLine("", err)
-				return
+				if c.ctx.Err() == nil {
+					c.sendLine("", err)
+				}
+				return err
 			}
 
-			c.Logger.Info("waitFileChanges", slog.String("nextFile", nxt.Name))
-			if nxt.Name != c.file.Name() {
-				err = c.openFile(nxt.Name)
-				gobase.True(err == nil)
+			// 每次文件变化都会唤醒，使用Debug避免Logger写入被监听的目录时循环触发
+			c.Logger.Debug("waitFileChanges", slog.String("nextFile", nxt.Name))
+			if nxt.Name != c.fileName {
+				if err = c.openWithRetry(nxt.Name); err != nil {
+					return err
+				}
 				c.curDateTimeLogMeta.cur = nxt.Ts
 			}
 
 		} else {
-			c.sendLine(line, err)
-			return
+			if c.ctx.Err() == nil {
+				c.sendLine(line, err)
+			}
+			return err
 		}
 	}
 }
 
-func (c *Consumer) Tell() (*SeekInfo, error) {
-	if c.file == nil {
-		return nil, nil
+// openWithRetry 打开失败时通过Lines返回错误并按指数退避重试，直到成功或ctx结束
+func (c *Consumer) openWithRetry(name string) error {
+	for retries := 0; ; retries++ {
+		err := c.openFile(name)
+		if err == nil {
+			return nil
+		}
+		c.Logger.Warn("openFile failed, retry later", slog.String("fileName", name), slog.Any("err", err))
+		c.sendLine("", err)
+		if !c.retryWait(retries) {
+			return c.ctx.Err()
+		}
+	}
+}
+
+// retryWait 第retries次重试前等待，ctx结束时返回false
+func (c *Consumer) retryWait(retries int) bool {
+	d := maxRetryInterval
+	if retries < 5 {
+		d = min(minRetryInterval<<retries, maxRetryInterval)
 	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
 
-	offset, err := c.file.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return nil, err
+// Tell 返回已读取的位置，其中可能包含尚未处理的行，需要至少一次投递时使用Ack
+func (c *Consumer) Tell() (*SeekInfo, error) {
+	c.posMu.Lock()
+	defer c.posMu.Unlock()
+
+	if c.fileName == "" {
+		return nil, nil
 	}
 
 	info := SeekInfo{
-		FileName: c.file.Name(),
-		Offset:   offset,
+		FileName: c.fileName,
+		Offset:   c.offset,
 		Whence:   0,
+		Inode:    c.inode,
 	}
 
 	return &info, nil
 }
 
+func (c *Consumer) seek(offset int64, whence int) error {
+	if c.decoder == nil {
+		pos, err := c.file.Seek(offset, whence)
+		if err != nil {
+			return err
+		}
+		c.posMu.Lock()
+		c.offset = pos
+		c.posMu.Unlock()
+		c.openReader()
+		return nil
+	}
+
+	// 压缩文件不支持随机访问，只能从头丢弃
+	if whence == io.SeekEnd && offset == 0 {
+		n, err := io.Copy(io.Discard, c.reader)
+		c.posMu.Lock()
+		c.offset += n
+		c.posMu.Unlock()
+		return err
+	}
+	if whence != io.SeekStart {
+		return fmt.Errorf("compressed file %s only supports io.SeekStart and seeking to the end", c.file.Name())
+	}
+	n, err := io.CopyN(io.Discard, c.reader, offset)
+	c.posMu.Lock()
+	c.offset += n
+	c.posMu.Unlock()
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+func (c *Consumer) startMultiline() {
+	if c.Multiline != nil {
+		c.multiline = newMultiline(*c.Multiline, c.emit)
+	}
+}
+
+// stopMultiline 读取协程退出时输出未结束的记录
+func (c *Consumer) stopMultiline() {
+	if c.multiline != nil {
+		c.multiline.close()
+	}
+}
+
 func (c *Consumer) sendLine(line string, err error) {
-	c.Lines <- &Line{Text: line, Err: err, Meta: LineMeta{FileName: c.file.Name()}}
+	l := &Line{Text: line, Err: err, Meta: LineMeta{
+		FileName:  c.fileName,
+		Offset:    c.lineStart,
+		EndOffset: c.offset,
+		Inode:     c.inode,
+	}, consumer: c}
+	if c.multiline != nil {
+		select {
+		case c.multiline.in <- l:
+		case <-c.ctx.Done():
+		}
+		return
+	}
+	c.emit(l)
+}
+
+// emit 停止后不再阻塞在Lines上
+func (c *Consumer) emit(l *Line) {
+	if c.Parser != nil && l.Err == nil {
+		l.Fields, l.ParseErr = c.Parser.Parse(l.Text)
+	}
+	select {
+	case c.Lines <- l:
+	case <-c.ctx.Done():
+	}
 }
 
 // readLine read a line unless meet a '\n' or some error except io.EOF
 func (c *Consumer) readLine() (string, error) {
 	var line string
+	c.lineStart = c.offset
 	for {
 		str, err := c.reader.ReadString('\n')
 		line += str
+		c.posMu.Lock()
+		c.offset += int64(len(str))
+		c.posMu.Unlock()
 		if err != nil {
 			// Note ReadString "returns the data read before the error" in
 			// case of an error, including EOF, so we return it as is. The
 			// caller is expected to process it if err is EOF.
 			if err == io.EOF && len(line) > 0 {
 				if !strings.HasSuffix(line, "\n") {
+					c.notifier.wait(c.ctx, 0)
+					if err := c.ctx.Err(); err != nil {
+						return line, err
+					}
+					if c.partialDone() {
+						c.Logger.Info("flush incomplete last line", slog.String("fileName", c.fileName))
+						return line, nil
+					}
 					continue
 				}
 			}
@@ -214,7 +494,37 @@ func (c *Consumer) readLine() (string, error) {
 	return line, nil
 }
 
+// partialDone 文件末尾的行不完整时，检查文件是否已不会再写入，e.g. 已被截断、已滚动到新文件
+func (c *Consumer) partialDone() bool {
+	if c.DateTimeLogLayout != nil {
+		for _, f := range c.getNextFile() {
+			if _, err := os.Stat(resolveFile(f.Name)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+
+	fi, err := c.file.Stat()
+	if err != nil || fi.Size() > c.offset {
+		return false
+	}
+	if fi.Size() < c.offset {
+		return true
+	}
+	id, err := fileID(c.FilePath)
+	if err != nil || id == c.inode {
+		return false
+	}
+	nfi, err := os.Stat(c.FilePath)
+	return err == nil && nfi.Size() > 0
+}
+
 func (c *Consumer) openReader() {
+	if c.decoder != nil {
+		c.reader = bufio.NewReaderSize(c.decoder, maxReadSize)
+		return
+	}
 	c.reader = bufio.NewReaderSize(c.file, maxReadSize)
 }
 
@@ -245,16 +555,33 @@ func (c *Consumer) getNextFile() []nxtFile {
 }
 
 func (c *Consumer) openFile(fName string) error {
-	file, err := os.Open(fName)
+	file, err := os.Open(resolveFile(fName))
 	if err != nil {
 		return err
 	}
 
+	decoder, err := mylog.NewDecompressReader(file.Name(), file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if file.Name() == fName {
+		decoder = nil
+	}
+
 	if c.file != nil {
-		c.Close()
+		c.closeFile()
 	}
 
+	inode, _ := openedFileID(file)
+
+	c.posMu.Lock()
 	c.file = file
+	c.fileName = fName
+	c.decoder = decoder
+	c.offset = 0
+	c.inode = inode
+	c.posMu.Unlock()
 
 	c.openReader()
 
@@ -268,15 +595,21 @@ func (c *Consumer) waitNxtFile() (nxtFile, error) {
 		return nxtFile{}, err
 	}
 
-	for {
-		time.Sleep(time.Second)
+	for first := true; ; first = false {
+		// 先检查一次，变化可能发生在等待之前
+		if !first {
+			c.notifier.wait(c.ctx, 0)
+			if err := c.ctx.Err(); err != nil {
+				return nxtFile{}, err
+			}
+		}
 
 		newFiles := c.getNextFile()
-		c.Logger.Info("getNextFile", slog.Any("name", gobase.AbbreviateArray(newFiles)))
+		c.Logger.Debug("getNextFile", slog.Any("name", gobase.AbbreviateArray(newFiles)))
 
 		var newFile nxtFile
 		for _, f := range newFiles {
-			_, err := os.Stat(f.Name)
+			_, err := os.Stat(resolveFile(f.Name))
 			if err == nil {
 				newFile = f
 				c.Logger.Info("newFile from getNextFile exist", slog.String("name", newFile.Name))
@@ -284,15 +617,16 @@ func (c *Consumer) waitNxtFile() (nxtFile, error) {
 			}
 		}
 
-		latest, err := os.Stat(c.file.Name())
+		// 文件可能已被压缩删除，使用已打开的句柄获取大小
+		latest, err := c.file.Stat()
 		if err != nil {
 			c.Logger.Warn("os.Stat encounter an error", slog.String("name", c.file.Name()), slog.Any("err", err))
 			return nxtFile{}, err
 		}
 
-		if size >= 0 && size < latest.Size() {
+		if c.decoder == nil && size >= 0 && size < latest.Size() {
 			return nxtFile{
-				Name: c.file.Name(),
+				Name: c.fileName,
 				Ts:   c.curDateTimeLogMeta.cur,
 			}, nil
 		}
@@ -303,11 +637,17 @@ func (c *Consumer) waitNxtFile() (nxtFile, error) {
 	}
 }
 
-func (c *Consumer) Close() {
+func (c *Consumer) closeFile() {
 	if c.file == nil {
 		return
 	}
 
+	if c.decoder != nil {
+		_ = c.decoder.Close()
+		c.decoder = nil
+	}
 	_ = c.file.Close()
+	c.posMu.Lock()
 	c.file = nil
+	c.posMu.Unlock()
 }
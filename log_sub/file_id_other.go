@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package log_sub
+
+import "os"
+
+// fileID 不支持获取文件标识的平台返回0，此时无法识别重命名滚动，只能识别截断
+func fileID(name string) (uint64, error) {
+	_, err := os.Stat(name)
+	return 0, err
+}
+
+func openedFileID(f *os.File) (uint64, error) {
+	return 0, nil
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package log_sub
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileIDOf(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// fileID 返回文件的inode，文件被重命名后不变
+func fileID(name string) (uint64, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return fileIDOf(fi), nil
+}
+
+func openedFileID(f *os.File) (uint64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fileIDOf(fi), nil
+}
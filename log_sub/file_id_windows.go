@@ -0,0 +1,24 @@
+package log_sub
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID 返回文件的file index，文件被重命名后不变
+func fileID(name string) (uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return openedFileID(f)
+}
+
+func openedFileID(f *os.File) (uint64, error) {
+	var d syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &d); err != nil {
+		return 0, err
+	}
+	return uint64(d.FileIndexHigh)<<32 | uint64(d.FileIndexLow), nil
+}
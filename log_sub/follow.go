@@ -0,0 +1,281 @@
+package log_sub
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errFileRemoved 空闲期间文件被删除且超过idleTimeout未重新创建
+var errFileRemoved = errors.New("file removed")
+
+// startFollow 跟踪Config.FilePath，到达文件末尾后检查文件是否增长、被截断或被重命名
+// 出错时通过Lines返回错误，并从已读取的位置重新打开
+func (c *Consumer) startFollow() (err error) {
+	defer func() {
+		c.release()
+		if c.onExit != nil {
+			c.onExit(err)
+		}
+	}()
+
+	if !c.acquire() {
+		return c.ctx.Err()
+	}
+
+	for retries := 0; ; retries++ {
+		err = c.resumeFollow()
+		if err == nil {
+			retries = 0
+			err = c.follow()
+		}
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+		if errors.Is(err, errFileRemoved) {
+			c.Logger.Info("stop following removed file", slog.String("fileName", c.FilePath))
+			return err
+		}
+
+		c.sendLine("", err)
+		// MultiConsumer的文件退出后由discover重新发现
+		if c.onExit != nil {
+			return err
+		}
+
+		c.Logger.Warn("follow failed, retry later", slog.String("fileName", c.FilePath), slog.Any("err", err))
+		if loc, _ := c.Tell(); loc != nil {
+			c.Location = loc
+		}
+		c.closeFile()
+		if !c.retryWait(retries) {
+			return c.ctx.Err()
+		}
+	}
+}
+
+// follow 读取到出错为止
+func (c *Consumer) follow() error {
+	for {
+		line, err := c.readLine()
+		if err == nil {
+			c.sendLine(line, nil)
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		if line != "" {
+			c.sendLine(line, nil)
+		}
+
+		if err = c.waitFollowChanges(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Consumer) resumeFollow() error {
+	if c.Location == nil {
+		return c.openFile(c.FilePath)
+	}
+	return c.reopen(*c.Location)
+}
+
+// reopen loc中的文件已被重命名时，按inode找到该文件读完后再切换到FilePath
+func (c *Consumer) reopen(loc SeekInfo) error {
+	name := loc.FileName
+	if loc.Inode != 0 {
+		name = c.findByInode(loc.FileName, loc.Inode)
+		if name == "" {
+			c.Logger.Warn("file of location not found, start from the beginning of FilePath",
+				slog.String("fileName", loc.FileName), slog.Uint64("inode", loc.Inode))
+			return c.openFile(c.FilePath)
+		}
+	}
+
+	if err := c.openFile(name); err != nil {
+		return err
+	}
+
+	fi, err := c.file.Stat()
+	if err != nil {
+		return err
+	}
+	if loc.Whence == io.SeekStart && loc.Offset > fi.Size() {
+		c.Logger.Warn("file truncated, start from the beginning",
+			slog.String("fileName", name), slog.Int64("offset", loc.Offset), slog.Int64("size", fi.Size()))
+		return nil
+	}
+	return c.seek(loc.Offset, loc.Whence)
+}
+
+// findByInode 依次查找原文件名、FilePath及FilePath滚动后的文件，e.g. app.log.1、app.log-20230815
+func (c *Consumer) findByInode(name string, inode uint64) string {
+	candidates := []string{name, c.FilePath}
+	if matches, err := filepath.Glob(c.FilePath + "?*"); err == nil {
+		candidates = append(candidates, matches...)
+	}
+
+	for _, cand := range candidates {
+		if id, err := fileID(cand); err == nil && id == inode {
+			return cand
+		}
+	}
+	return ""
+}
+
+// waitFollowChanges 返回后可以继续读取，先检查一次，变化可能发生在等待之前
+func (c *Consumer) waitFollowChanges() error {
+	idleSince := time.Now()
+	for {
+		changed, err := c.checkFollow()
+		if err != nil || changed {
+			return err
+		}
+		if c.idleTimeout > 0 && time.Since(idleSince) >= c.idleTimeout {
+			return c.idle()
+		}
+
+		var max time.Duration
+		if c.idleTimeout > 0 {
+			max = c.idleTimeout - time.Since(idleSince)
+		}
+		c.notifier.wait(c.ctx, max)
+		if err = c.ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// checkFollow 检查文件是否增长、被截断或FilePath已指向新文件
+func (c *Consumer) checkFollow() (bool, error) {
+	fi, err := c.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if fi.Size() > c.offset {
+		return true, nil
+	}
+	if fi.Size() < c.offset {
+		// copytruncate: 文件被截断后从头读取
+		c.Logger.Info("file truncated", slog.String("fileName", c.fileName),
+			slog.Int64("offset", c.offset), slog.Int64("size", fi.Size()))
+		return true, c.seek(0, io.SeekStart)
+	}
+
+	id, err := fileID(c.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if id == c.inode {
+		return false, nil
+	}
+
+	// 新文件有数据后再切换，此前旧文件可能仍在被写入
+	nfi, err := os.Stat(c.FilePath)
+	if err != nil || nfi.Size() == 0 {
+		return false, nil
+	}
+
+	c.Logger.Info("file rotated", slog.String("from", c.fileName), slog.String("to", c.FilePath))
+	if err = c.drain(); err != nil {
+		return false, err
+	}
+	return true, c.openFile(c.FilePath)
+}
+
+// drain 切换到新文件前读完旧文件中在检查之后写入的行，末尾不完整的行也一并输出
+func (c *Consumer) drain() error {
+	for {
+		c.lineStart = c.offset
+		str, err := c.reader.ReadString('\n')
+		c.posMu.Lock()
+		c.offset += int64(len(str))
+		c.posMu.Unlock()
+		if str != "" {
+			c.sendLine(strings.TrimRight(str, "\n"), nil)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// idle 关闭文件并释放句柄配额，文件有变化后重新打开
+func (c *Consumer) idle() error {
+	loc := SeekInfo{FileName: c.fileName, Offset: c.offset, Whence: io.SeekStart, Inode: c.inode}
+	c.closeFile()
+	c.release()
+	c.Logger.Debug("close idle file", slog.String("fileName", loc.FileName))
+
+	var missing time.Time
+	for {
+		var max time.Duration
+		if !missing.IsZero() {
+			max = c.idleTimeout - time.Since(missing)
+		}
+		c.notifier.wait(c.ctx, max)
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		id, err := fileID(c.FilePath)
+		if os.IsNotExist(err) {
+			if missing.IsZero() {
+				missing = time.Now()
+			} else if time.Since(missing) >= c.idleTimeout {
+				return errFileRemoved
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		missing = time.Time{}
+
+		if id == loc.Inode {
+			fi, err := os.Stat(c.FilePath)
+			if err != nil || fi.Size() == loc.Offset {
+				continue
+			}
+		}
+		break
+	}
+
+	if !c.acquire() {
+		return c.ctx.Err()
+	}
+	return c.reopen(loc)
+}
+
+// acquire ctx结束时返回false
+func (c *Consumer) acquire() bool {
+	if c.sem == nil || c.semHeld {
+		return true
+	}
+	select {
+	case c.sem <- struct{}{}:
+		c.semHeld = true
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+func (c *Consumer) release() {
+	if c.sem != nil && c.semHeld {
+		<-c.sem
+		c.semHeld = false
+	}
+}
@@ -0,0 +1,152 @@
+package log_sub
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// grokPatterns 常用的grok模式，参考logstash的定义并改写为RE2语法
+var grokPatterns = map[string]string{
+	"USERNAME":     `[a-zA-Z0-9._-]+`,
+	"USER":         `%{USERNAME}`,
+	"INT":          `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":    `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":       `%{BASE10NUM}`,
+	"POSINT":       `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":    `\b(?:[0-9]+)\b`,
+	"WORD":         `\b\w+\b`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`)",
+	"QS":           `%{QUOTEDSTRING}`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`,
+	"IPV6":     `(?:(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}|::(?:ffff:)?%{IPV4}|(?:(?:[0-9A-Fa-f]{1,4}:){0,6}[0-9A-Fa-f]{1,4})?::(?:(?:[0-9A-Fa-f]{1,4}:){0,6}[0-9A-Fa-f]{1,4})?)`,
+	"IP":       `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME": `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST": `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"UNIXPATH":     `(?:/[\w_%!$@:.,+~-]*)+`,
+	"PATH":         `%{UNIXPATH}`,
+	"URIPROTO":     `[A-Za-z][A-Za-z0-9+\-.]+`,
+	"URIHOST":      `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":          `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATHPARAM})?`,
+
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+	"YEAR":              `\d{4}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|panic|alert|emerg(?:ency)?)`,
+
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+	// NGINXACCESS nginx默认的combined格式
+	"NGINXACCESS": `%{COMBINEDAPACHELOG}`,
+}
+
+// grokRefRe %{PATTERN}、%{PATTERN:field}、%{PATTERN:field:int|float}
+var grokRefRe = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?(?::(\w+))?\}`)
+
+const maxGrokDepth = 32
+
+type grokField struct {
+	name string
+	typ  string
+}
+
+// NewGrokParser 按grok语法解析，%{PATTERN:field}的匹配作为字段，可用 %{NUMBER:bytes:int} 转换为int64，float转换为float64
+// patterns 自定义模式，同名时覆盖内置模式。内置模式包括:
+// WORD NOTSPACE SPACE DATA GREEDYDATA INT NUMBER POSINT QS UUID IP IPV4 IPV6 HOSTNAME IPORHOST HOSTPORT
+// PATH URI URIPATH URIPATHPARAM TIMESTAMP_ISO8601 HTTPDATE SYSLOGTIMESTAMP LOGLEVEL
+// COMMONAPACHELOG COMBINEDAPACHELOG NGINXACCESS 等
+// e.g. NewGrokParser(`%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} %{GREEDYDATA:msg}`, nil)
+func NewGrokParser(pattern string, patterns map[string]string) (Parser, error) {
+	lookup := func(name string) (string, bool) {
+		if p, ok := patterns[name]; ok {
+			return p, true
+		}
+		p, ok := grokPatterns[name]
+		return p, ok
+	}
+
+	fields := make(map[string]grokField)
+	var expand func(p string, depth int) (string, error)
+	expand = func(p string, depth int) (string, error) {
+		if depth > maxGrokDepth {
+			return "", fmt.Errorf("grok pattern nested too deep, maybe recursive: %s", p)
+		}
+
+		var err error
+		out := grokRefRe.ReplaceAllStringFunc(p, func(ref string) string {
+			if err != nil {
+				return ""
+			}
+			m := grokRefRe.FindStringSubmatch(ref)
+			def, ok := lookup(m[1])
+			if !ok {
+				err = fmt.Errorf("grok pattern %s not found", m[1])
+				return ""
+			}
+			sub, e := expand(def, depth+1)
+			if e != nil {
+				err = e
+				return ""
+			}
+			if m[2] == "" {
+				return "(?:" + sub + ")"
+			}
+			switch m[3] {
+			case "", "int", "float":
+			default:
+				err = fmt.Errorf("invalid grok type %s in %s", m[3], ref)
+				return ""
+			}
+			group := "grok" + strconv.Itoa(len(fields))
+			fields[group] = grokField{name: m[2], typ: m[3]}
+			return "(?P<" + group + ">" + sub + ")"
+		})
+		return out, err
+	}
+
+	expr, err := expand(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compile grok pattern %q failed. err=%v", pattern, err)
+	}
+
+	p := &regexParser{re: re, names: append([]string(nil), re.SubexpNames()...)}
+	p.convs = make([]func(string) (any, error), len(p.names))
+	for i, group := range p.names {
+		f, ok := fields[group]
+		if !ok {
+			continue
+		}
+		p.names[i] = f.name
+		switch f.typ {
+		case "int":
+			p.convs[i] = func(s string) (any, error) { return strconv.ParseInt(s, 10, 64) }
+		case "float":
+			p.convs[i] = func(s string) (any, error) { return strconv.ParseFloat(s, 64) }
+		}
+	}
+	return p, nil
+}
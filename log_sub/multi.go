@@ -0,0 +1,257 @@
+package log_sub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	mylog "github.com/BabySid/gobase/log"
+)
+
+type MultiConfig struct {
+	// Patterns filepath.Glob格式，e.g. /var/log/svc-*/app.log
+	Patterns []string
+	// DiscoverInterval 查找新文件的间隔，默认5s
+	DiscoverInterval time.Duration
+	// MaxOpenFiles 同时打开的文件数上限，超出的文件等待其他文件空闲关闭后再读取，默认64
+	MaxOpenFiles int
+	// IdleTimeout 超过该时间没有新数据的文件被关闭，有新数据后重新打开，默认5min
+	// 文件被删除且超过IdleTimeout未重新创建时不再跟踪
+	IdleTimeout time.Duration
+	// PollInterval 同Config.PollInterval
+	PollInterval time.Duration
+	// Multiline 同Config.Multiline，对每个文件分别合并
+	Multiline *MultilineConfig
+	// Parser 同Config.Parser，各文件的协程并发调用
+	Parser Parser
+	// ManualStart 同Config.ManualStart
+	ManualStart bool
+
+	// Checkpoint 不为nil时为每个文件返回保存Ack位置的CheckpointStore，e.g. NewFileCheckpointStores(dir)
+	// 文件有保存的位置时从该位置继续；否则启动时已存在的文件从末尾开始读取，之后出现的文件从头读取
+	Checkpoint func(fileName string) CheckpointStore
+
+	Logger mylog.Logger
+}
+
+// MultiConsumer 跟踪所有匹配Patterns的文件，每个文件的处理方式同Config.FilePath，
+// 所有文件的日志汇总到Lines，通过Line.Meta.FileName区分
+// 启动时已存在的文件只读取新写入的内容，需要重启后不丢失未处理的行时配置Checkpoint并调用Ack
+type MultiConsumer struct {
+	Lines chan *Line
+	MultiConfig
+
+	sem chan struct{}
+
+	mu        sync.Mutex
+	consumers map[string]*Consumer
+	// positions 因错误退出的文件的位置，重新发现时从该位置继续
+	positions map[string]*SeekInfo
+
+	runMu  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewMultiConsumer(config MultiConfig) (*MultiConsumer, error) {
+	if len(config.Patterns) == 0 {
+		return nil, errors.New("invalid config: Patterns must be set")
+	}
+	if config.Multiline != nil {
+		if err := config.Multiline.verify(); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range config.Patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q. err=%v", p, err)
+		}
+	}
+
+	if config.Logger == nil {
+		config.Logger = mylog.NewSLogger(mylog.WithOutFile(mylog.StdErr))
+	}
+	// 自定义的Logger实现没有Named时保持原样
+	if l, ok := config.Logger.(mylog.ExtLogger); ok {
+		config.Logger = l.Named(LoggerName)
+	}
+	if config.DiscoverInterval <= 0 {
+		config.DiscoverInterval = 5 * time.Second
+	}
+	if config.MaxOpenFiles <= 0 {
+		config.MaxOpenFiles = 64
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 5 * time.Minute
+	}
+
+	m := MultiConsumer{
+		Lines:       make(chan *Line, defaultBufSize),
+		MultiConfig: config,
+		sem:         make(chan struct{}, config.MaxOpenFiles),
+		consumers:   make(map[string]*Consumer),
+		positions:   make(map[string]*SeekInfo),
+	}
+	if !config.ManualStart {
+		if err := m.Start(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Start 同Consumer.Start，所有文件的协程退出后关闭Lines
+func (m *MultiConsumer) Start(ctx context.Context) error {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+
+	if m.done != nil {
+		return errors.New("consumer already started")
+	}
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		defer close(m.Lines)
+		defer m.wg.Wait()
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for first := true; ; first = false {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-timer.C:
+				m.discover(first)
+				timer.Reset(m.DiscoverInterval)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop 同Consumer.Stop
+func (m *MultiConsumer) Stop() {
+	m.runMu.Lock()
+	cancel, done := m.cancel, m.done
+	m.runMu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Files 返回正在跟踪的文件
+func (m *MultiConsumer) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make([]string, 0, len(m.consumers))
+	for name := range m.consumers {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Ack 同Consumer.Ack，保存到该行所在文件的CheckpointStore
+func (m *MultiConsumer) Ack(line *Line) error {
+	if line.consumer == nil {
+		return errors.New("line is not read by a consumer")
+	}
+	return line.consumer.Ack(line)
+}
+
+// discover existing为true时找到的是启动前已存在的文件
+func (m *MultiConsumer) discover(existing bool) {
+	for _, p := range m.Patterns {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			m.Logger.Warn("glob failed", slog.String("pattern", p), slog.Any("err", err))
+			continue
+		}
+		for _, name := range matches {
+			if fi, err := os.Stat(name); err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			m.follow(name, existing)
+		}
+	}
+}
+
+func (m *MultiConsumer) follow(name string, existing bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.consumers[name]; ok {
+		return
+	}
+
+	loc := m.positions[name]
+	var store CheckpointStore
+	if m.Checkpoint != nil {
+		store = m.Checkpoint(name)
+		if loc == nil {
+			acked, err := store.Load()
+			if err != nil {
+				m.Logger.Warn("load checkpoint failed", slog.String("fileName", name), slog.Any("err", err))
+			}
+			loc = acked
+		}
+	}
+	if loc == nil && existing {
+		loc = &SeekInfo{FileName: name, Offset: 0, Whence: io.SeekEnd}
+	}
+
+	c := &Consumer{
+		Lines: m.Lines,
+		Config: Config{
+			Location:     loc,
+			FilePath:     name,
+			Checkpoint:   store,
+			PollInterval: m.PollInterval,
+			Multiline:    m.Multiline,
+			Parser:       m.Parser,
+			Logger:       m.Logger,
+		},
+		idleTimeout: m.IdleTimeout,
+		sem:         m.sem,
+	}
+	c.onExit = func(err error) {
+		m.exited(name, c, err)
+	}
+	c.ctx = m.ctx
+	delete(m.positions, name)
+	m.consumers[name] = c
+
+	m.Logger.Info("follow new file", slog.String("fileName", name))
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		c.consume()
+	}()
+}
+
+func (m *MultiConsumer) exited(name string, c *Consumer, err error) {
+	pos, _ := c.Tell()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.consumers, name)
+	if !errors.Is(err, errFileRemoved) && pos != nil {
+		m.positions[name] = pos
+	}
+}
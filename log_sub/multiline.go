@@ -0,0 +1,125 @@
+package log_sub
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MultilineConfig 将多行组成的一条记录(e.g. 异常堆栈、格式化的JSON)合并为一个Line
+// Line.Text为各行以'\n'连接，Meta.Offset为首行的行首，Meta.EndOffset为末行的下一行行首
+type MultilineConfig struct {
+	// Start 匹配记录首行，不匹配的行追加到上一条记录，e.g. `^\d{4}-\d{2}-\d{2}`
+	Start *regexp.Regexp
+	// Indent 以空格或tab开头的行追加到上一条记录，e.g. Go/Python的堆栈
+	// 与Start同时设置时满足任一条件即为续行
+	Indent bool
+	// MaxLines 一条记录的最大行数，达到后输出，其余的续行作为新的记录，默认500
+	MaxLines int
+	// FlushTimeout 超过该时间没有新的行时输出未结束的记录，默认1s
+	FlushTimeout time.Duration
+}
+
+func (cfg *MultilineConfig) verify() error {
+	if cfg.Start == nil && !cfg.Indent {
+		return errors.New("invalid config: one of Multiline.Start and Multiline.Indent must be set")
+	}
+	return nil
+}
+
+// multiline 在独立的协程中合并读取协程的行，以便读取协程等待文件变化时仍可按FlushTimeout输出
+type multiline struct {
+	MultilineConfig
+	in   chan *Line
+	emit func(l *Line)
+	done chan struct{}
+
+	pending []*Line
+}
+
+func newMultiline(cfg MultilineConfig, emit func(l *Line)) *multiline {
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = 500
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = time.Second
+	}
+	m := &multiline{
+		MultilineConfig: cfg,
+		in:              make(chan *Line, defaultBufSize),
+		emit:            emit,
+		done:            make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+func (m *multiline) run() {
+	defer close(m.done)
+	timer := time.NewTimer(m.FlushTimeout)
+	timer.Stop()
+	for {
+		select {
+		case l, ok := <-m.in:
+			if !ok {
+				m.flush()
+				return
+			}
+			m.add(l)
+			if len(m.pending) > 0 {
+				timer.Reset(m.FlushTimeout)
+			}
+		case <-timer.C:
+			m.flush()
+		}
+	}
+}
+
+func (m *multiline) add(l *Line) {
+	if l.Err != nil {
+		m.flush()
+		m.emit(l)
+		return
+	}
+
+	if len(m.pending) > 0 {
+		first := m.pending[0]
+		if !m.continuation(l.Text) || len(m.pending) >= m.MaxLines ||
+			first.Meta.FileName != l.Meta.FileName || first.Meta.Inode != l.Meta.Inode {
+			m.flush()
+		}
+	}
+	m.pending = append(m.pending, l)
+}
+
+func (m *multiline) continuation(text string) bool {
+	if m.Start != nil && !m.Start.MatchString(text) {
+		return true
+	}
+	return m.Indent && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t"))
+}
+
+func (m *multiline) flush() {
+	if len(m.pending) == 0 {
+		return
+	}
+
+	record := m.pending[0]
+	if len(m.pending) > 1 {
+		texts := make([]string, len(m.pending))
+		for i, l := range m.pending {
+			texts[i] = l.Text
+		}
+		record.Text = strings.Join(texts, "\n")
+		record.Meta.EndOffset = m.pending[len(m.pending)-1].Meta.EndOffset
+	}
+	m.pending = m.pending[:0]
+	m.emit(record)
+}
+
+// close 等待未结束的记录输出后返回
+func (m *multiline) close() {
+	close(m.in)
+	<-m.done
+}
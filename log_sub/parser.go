@@ -0,0 +1,155 @@
+package log_sub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Parser 将Line.Text解析为Line.Fields，失败时设置Line.ParseErr，不影响后续的行
+type Parser interface {
+	Parse(text string) (map[string]any, error)
+}
+
+// ParserFunc 允许使用普通函数作为Parser
+type ParserFunc func(text string) (map[string]any, error)
+
+func (f ParserFunc) Parse(text string) (map[string]any, error) {
+	return f(text)
+}
+
+var ErrNoMatch = errors.New("line does not match")
+
+// NewJSONParser 解析每行一个JSON对象的日志，e.g. log.WithJsonFormat的输出
+// 嵌套对象为map[string]any，数字为json.Number以免丢失int64的精度
+func NewJSONParser() Parser {
+	return ParserFunc(func(text string) (map[string]any, error) {
+		dec := json.NewDecoder(bytes.NewReader([]byte(text)))
+		dec.UseNumber()
+		var fields map[string]any
+		if err := dec.Decode(&fields); err != nil {
+			return nil, err
+		}
+		if fields == nil {
+			return nil, errors.New("not a json object")
+		}
+		return fields, nil
+	})
+}
+
+// NewLogfmtParser 解析 k=v k2="quoted value" 格式的日志，值均为string，没有'='的key值为"true"
+func NewLogfmtParser() Parser {
+	return ParserFunc(parseLogfmt)
+}
+
+// NewSlogTextParser 解析slog.TextHandler及log.WithLogfmtFormat的输出，time为RFC3339格式时转换为time.Time，
+// group中的属性保留为 group.key
+func NewSlogTextParser() Parser {
+	return ParserFunc(func(text string) (map[string]any, error) {
+		fields, err := parseLogfmt(text)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := fields["time"].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				fields["time"] = t
+			}
+		}
+		return fields, nil
+	})
+}
+
+func parseLogfmt(text string) (map[string]any, error) {
+	fields := make(map[string]any)
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+
+		key, n, err := logfmtToken(text[i:], true)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key at %d. err=%v", i, err)
+		}
+		i += n
+		if i >= len(text) || text[i] != '=' {
+			fields[key] = "true"
+			continue
+		}
+		i++
+
+		val, n, err := logfmtToken(text[i:], false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s at %d. err=%v", key, i, err)
+		}
+		i += n
+		fields[key] = val
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("empty logfmt line")
+	}
+	return fields, nil
+}
+
+// logfmtToken 返回token及其在s中的长度，带引号的token按Go字符串字面量解析
+func logfmtToken(s string, isKey bool) (string, int, error) {
+	if len(s) > 0 && s[0] == '"' {
+		q, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", 0, err
+		}
+		v, err := strconv.Unquote(q)
+		return v, len(q), err
+	}
+
+	n := 0
+	for n < len(s) && s[n] != ' ' && s[n] != '\t' && !(isKey && s[n] == '=') {
+		n++
+	}
+	if isKey && n == 0 {
+		return "", 0, errors.New("empty key")
+	}
+	return s[:n], n, nil
+}
+
+// NewRegexParser 命名分组作为字段，值为string，未参与匹配的分组不输出
+// e.g. `^(?P<ip>\S+) \S+ \S+ \[(?P<time>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d+)`
+func NewRegexParser(re *regexp.Regexp) Parser {
+	return &regexParser{re: re, names: re.SubexpNames()}
+}
+
+type regexParser struct {
+	re    *regexp.Regexp
+	names []string
+	// convs 字段的类型转换，由grok设置
+	convs []func(string) (any, error)
+}
+
+func (p *regexParser) Parse(text string) (map[string]any, error) {
+	idx := p.re.FindStringSubmatchIndex(text)
+	if idx == nil {
+		return nil, ErrNoMatch
+	}
+
+	fields := make(map[string]any)
+	for i, name := range p.names {
+		if name == "" || idx[2*i] < 0 {
+			continue
+		}
+		v := text[idx[2*i]:idx[2*i+1]]
+		if p.convs == nil || p.convs[i] == nil {
+			fields[name] = v
+			continue
+		}
+		cv, err := p.convs[i](v)
+		if err != nil {
+			return nil, fmt.Errorf("convert %s=%q failed. err=%v", name, v, err)
+		}
+		fields[name] = cv
+	}
+	return fields, nil
+}
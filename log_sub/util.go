@@ -1,19 +1,37 @@
 package log_sub
 
 import (
-	"github.com/BabySid/gobase"
+	"fmt"
+	"os"
 	"path/filepath"
 	"time"
+
+	mylog "github.com/BabySid/gobase/log"
 )
 
-func verifyLogStep(layout DateTimeLayout, name string) int {
+func verifyLogStep(layout DateTimeLayout, name string) (int, error) {
 	fName := filepath.Base(name)
 	startTime, err := time.Parse(layout.Layout, fName)
-	gobase.True(err == nil, "parse(%s %s) failed. err=%v", layout.Layout, fName, err)
+	if err != nil {
+		return 0, fmt.Errorf("parse(%s %s) failed. err=%v", layout.Layout, fName, err)
+	}
 
 	startTime = startTime.Add(time.Hour)
 	if startTime.Format(layout.Layout) == fName {
-		return daily
+		return daily, nil
+	}
+	return hourly, nil
+}
+
+// resolveFile 原文件不存在时，尝试查找被压缩后的文件
+func resolveFile(name string) string {
+	if _, err := os.Stat(name); err == nil {
+		return name
+	}
+	for _, ext := range mylog.CompressExts {
+		if _, err := os.Stat(name + ext); err == nil {
+			return name + ext
+		}
 	}
-	return hourly
+	return name
 }
@@ -0,0 +1,86 @@
+package log_sub
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	// watchRescanInterval 使用inotify时也定期检查，避免遗漏事件(e.g. NFS上不产生事件)
+	watchRescanInterval = time.Minute
+)
+
+var errWatchUnsupported = errors.New("file watch is not supported on this platform")
+
+// dirWatch 目录中的文件被创建、写入、重命名或删除时通知C
+type dirWatch struct {
+	C    chan struct{}
+	stop func()
+	// gone 目录被删除、移动或inotify出错后不再有事件
+	gone atomic.Bool
+}
+
+// changeNotifier 等待目录中的文件发生变化，不支持inotify时退化为按pollInterval轮询
+type changeNotifier struct {
+	dir          string
+	watch        *dirWatch
+	pollInterval time.Duration
+	// lost 监听失效后尚未重新监听
+	lost bool
+}
+
+func newChangeNotifier(dir string, pollInterval time.Duration) *changeNotifier {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	n := &changeNotifier{dir: dir, pollInterval: pollInterval}
+	if w, err := watchDir(dir); err == nil {
+		n.watch = w
+	}
+	return n
+}
+
+// wait 在有变化、ctx结束或最多等待max后返回，max<=0时不限制
+func (n *changeNotifier) wait(ctx context.Context, max time.Duration) {
+	if n.watch != nil && n.watch.gone.Load() {
+		// 监听失效后按pollInterval轮询，并在每次等待前尝试重新监听，e.g. 目录被重新创建
+		n.close()
+		n.lost = true
+	}
+	if n.lost {
+		if w, err := watchDir(n.dir); err == nil {
+			// 重新监听前的变化没有事件，直接返回由调用方检查
+			n.watch, n.lost = w, false
+			return
+		}
+	}
+
+	d := watchRescanInterval
+	var changed chan struct{}
+	if n.watch != nil {
+		changed = n.watch.C
+	} else {
+		d = n.pollInterval
+	}
+	if max > 0 && max < d {
+		d = max
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-changed:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (n *changeNotifier) close() {
+	if n.watch != nil {
+		n.watch.stop()
+		n.watch = nil
+	}
+}
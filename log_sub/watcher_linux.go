@@ -0,0 +1,192 @@
+//go:build linux
+
+package log_sub
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_MODIFY | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotify 进程内共享一个inotify实例，同一目录只添加一次watch
+type inotify struct {
+	fd int
+
+	mu   sync.Mutex
+	wds  map[int]string
+	dirs map[string]*inotifyDir
+	// closed 读取出错后不再添加监听
+	closed bool
+}
+
+type inotifyDir struct {
+	wd   int
+	subs map[*dirWatch]struct{}
+}
+
+var (
+	inotifyOnce     sync.Once
+	inotifyInstance *inotify
+	inotifyErr      error
+)
+
+func watchDir(dir string) (*dirWatch, error) {
+	inotifyOnce.Do(func() {
+		fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+		if err != nil {
+			inotifyErr = err
+			return
+		}
+		inotifyInstance = &inotify{fd: fd, wds: make(map[int]string), dirs: make(map[string]*inotifyDir)}
+		go inotifyInstance.run()
+	})
+	if inotifyErr != nil {
+		return nil, inotifyErr
+	}
+	return inotifyInstance.add(filepath.Clean(dir))
+}
+
+func (in *inotify) add(dir string) (*dirWatch, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.closed {
+		return nil, errors.New("inotify closed")
+	}
+	d, ok := in.dirs[dir]
+	if !ok {
+		wd, err := unix.InotifyAddWatch(in.fd, dir, inotifyMask)
+		if err != nil {
+			return nil, err
+		}
+		d = &inotifyDir{wd: wd, subs: make(map[*dirWatch]struct{})}
+		in.dirs[dir] = d
+		in.wds[wd] = dir
+	}
+
+	w := &dirWatch{C: make(chan struct{}, 1)}
+	w.stop = func() {
+		in.remove(dir, w)
+	}
+	d.subs[w] = struct{}{}
+	return w, nil
+}
+
+func (in *inotify) remove(dir string, w *dirWatch) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	d, ok := in.dirs[dir]
+	if !ok {
+		return
+	}
+	if _, ok = d.subs[w]; !ok {
+		return
+	}
+	delete(d.subs, w)
+	if len(d.subs) == 0 {
+		_, _ = unix.InotifyRmWatch(in.fd, uint32(d.wd))
+		delete(in.dirs, dir)
+		delete(in.wds, d.wd)
+	}
+}
+
+func (in *inotify) run() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(in.fd, buf)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			in.closeAll()
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			switch {
+			case ev.Mask&unix.IN_Q_OVERFLOW != 0:
+				in.broadcastAll()
+			case ev.Mask&(unix.IN_IGNORED|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0:
+				in.drop(int(ev.Wd), ev.Mask&unix.IN_IGNORED == 0)
+			default:
+				in.broadcast(int(ev.Wd))
+			}
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+		}
+	}
+}
+
+func (in *inotify) broadcast(wd int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if d, ok := in.dirs[in.wds[wd]]; ok {
+		for w := range d.subs {
+			notify(w)
+		}
+	}
+}
+
+// drop 目录被删除或移动后监听失效，通知订阅方改为轮询
+func (in *inotify) drop(wd int, rmWatch bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	dir, ok := in.wds[wd]
+	if !ok {
+		return
+	}
+	if rmWatch {
+		_, _ = unix.InotifyRmWatch(in.fd, uint32(wd))
+	}
+	d := in.dirs[dir]
+	delete(in.dirs, dir)
+	delete(in.wds, wd)
+	for w := range d.subs {
+		w.gone.Store(true)
+		notify(w)
+	}
+}
+
+// closeAll 读取失败后所有监听失效
+func (in *inotify) closeAll() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.closed = true
+	for dir, d := range in.dirs {
+		for w := range d.subs {
+			w.gone.Store(true)
+			notify(w)
+		}
+		delete(in.dirs, dir)
+		delete(in.wds, d.wd)
+	}
+}
+
+// broadcastAll 事件队列溢出时通知所有订阅方重新检查
+func (in *inotify) broadcastAll() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for _, d := range in.dirs {
+		for w := range d.subs {
+			notify(w)
+		}
+	}
+}
+
+func notify(w *dirWatch) {
+	select {
+	case w.C <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package log_sub
+
+func watchDir(dir string) (*dirWatch, error) {
+	return nil, errWatchUnsupported
+}